@@ -0,0 +1,13 @@
+#!/bin/bash
+export PATH="$PATH:/usr/local/go/bin"
+export GOPATH=~/goroot
+
+go get "github.com/gdm85/go-dockerclient" "github.com/gdm85/goopt" "github.com/gdm85/go-libshell" || exit $?
+
+## build without debug information
+go build -ldflags "-w -s" || exit $?
+
+## symlinks named after each tool dispatch to the same binary
+if [[ "$1" == "--links" ]]; then
+	./docker-cli-tools install-links --force
+fi
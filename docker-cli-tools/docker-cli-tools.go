@@ -0,0 +1,181 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package main
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/cliplugin"
+	"github.com/gdm85/docker-cli-tools/internal/tools/cpukillers"
+	"github.com/gdm85/docker-cli-tools/internal/tools/dns"
+	"github.com/gdm85/docker-cli-tools/internal/tools/grep"
+	"github.com/gdm85/docker-cli-tools/internal/tools/hosts"
+	"github.com/gdm85/docker-cli-tools/internal/tools/images"
+	"github.com/gdm85/docker-cli-tools/internal/tools/ipv4"
+	"github.com/gdm85/goopt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// all tools bundled in the multicall binary, by command name
+var tools = map[string]func(){
+	"docker-cpu-killers": cpukillers.Main,
+	"docker-dns":         dns.Main,
+	"docker-grep":        grep.Main,
+	"docker-hosts":       hosts.Main,
+	"docker-images":      images.Main,
+	"docker-ipv4":        ipv4.Main,
+}
+
+// lookup finds a tool by command name, or by its Docker CLI plugin executable name
+func lookup(name string) (func(), bool) {
+	for toolName, run := range tools {
+		if name == toolName || name == "docker-"+cliplugin.PluginName(toolName) {
+			return run, true
+		}
+	}
+	return nil, false
+}
+
+func toolNames() []string {
+	names := []string{}
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func showUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: docker-cli-tools command [options] [arguments]")
+	fmt.Fprintln(os.Stderr, "       docker-cli-tools install-links [--force] [--plugins] [directory]")
+	fmt.Fprintln(os.Stderr, "Available commands:")
+	for _, name := range toolNames() {
+		fmt.Fprintf(os.Stderr, "  %s\n", strings.TrimPrefix(name, "docker-"))
+	}
+	fmt.Fprintln(os.Stderr, "Each command can also be run through a symlink named after it, e.g. docker-hosts")
+	fmt.Fprintln(os.Stderr, "docker-cli-tools is licensed under GNU GPLv2")
+}
+
+// installLinks creates a symlink to this executable for each tool
+func installLinks() {
+	goopt.Description = func() string {
+		return "Create a symlink for each of the docker-cli-tools commands."
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "docker-cli-tools install-links"
+	force := goopt.Flag([]string{"-f", "--force"}, []string{}, "replace existing files", "")
+	plugins := goopt.Flag([]string{"--plugins"}, []string{}, "install as Docker CLI plugins, by default in ~/.docker/cli-plugins", "")
+	goopt.Parse(nil)
+
+	if len(goopt.Args) > 1 {
+		showUsage()
+		os.Exit(1)
+	}
+
+	self, err := os.Executable()
+	if err == nil {
+		self, err = filepath.EvalSymlinks(self)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-cli-tools: cannot locate own executable: %s\n", err)
+		os.Exit(1)
+	}
+
+	// by default links are placed next to the executable
+	dir := filepath.Dir(self)
+	if *plugins {
+		dir = filepath.Join(os.Getenv("HOME"), ".docker", "cli-plugins")
+		if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+			dir = filepath.Join(configDir, "cli-plugins")
+		}
+	}
+	if len(goopt.Args) == 1 {
+		dir = goopt.Args[0]
+	}
+	if *plugins {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-cli-tools: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	for _, name := range toolNames() {
+		// plugin names cannot contain dashes
+		if *plugins {
+			name = "docker-" + cliplugin.PluginName(name)
+		}
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); err == nil {
+			if !*force {
+				fmt.Fprintf(os.Stderr, "docker-cli-tools: '%s' already exists, use --force to replace it\n", link)
+				os.Exit(2)
+			}
+			err = os.Remove(link)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "docker-cli-tools: %s\n", err)
+				os.Exit(2)
+			}
+		}
+
+		err := os.Symlink(self, link)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-cli-tools: %s\n", err)
+			os.Exit(2)
+		}
+		fmt.Printf("%s -> %s\n", link, self)
+	}
+}
+
+func main() {
+	// invoked through a symlink named after a tool
+	if run, ok := lookup(filepath.Base(os.Args[0])); ok {
+		run()
+		return
+	}
+
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	command := os.Args[1]
+	if command == "install-links" {
+		os.Args = os.Args[1:]
+		installLinks()
+		return
+	}
+
+	name := "docker-" + strings.TrimPrefix(command, "docker-")
+	run, ok := lookup(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "docker-cli-tools: unknown command '%s'\n", command)
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	// the tool sees itself as invoked directly
+	os.Args = append([]string{name}, os.Args[2:]...)
+	run()
+}
@@ -20,77 +20,9 @@ Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
 package main
 
 import (
-	"fmt"
-	"github.com/gdm85/go-dockerclient"
-	"os"
-	"strings"
+	"github.com/gdm85/docker-cli-tools/internal/tools/images"
 )
 
-var Docker *docker.Client
-var inspectCache map[string]*docker.Container
-
-func init() {
-	var err error
-	Docker, err = docker.NewClient("unix:///var/run/docker.sock")
-	if err != nil {
-		panic(err)
-	}
-}
-
-func showUsage() {
-	fmt.Fprintln(os.Stderr, "Usage: docker-images [partial-match]")
-	fmt.Fprintln(os.Stderr, "docker-images is part of docker-cli-tools and licensed under GNU GPLv2")
-}
-
-func getNamesOrIDs(image *docker.APIImages) []string {
-	buffer := []string{}
-	for _, name := range image.RepoTags {
-		if name == "<none>:<none>" {
-			buffer = append(buffer, image.ID)
-		} else {
-			buffer = append(buffer, name)
-		}
-	}
-
-	return buffer
-}
-
-func display(image *docker.APIImages, name string) {
-	fmt.Println(name)
-}
-
 func main() {
-	// if no arguments specified, show help and exit with failure
-	if len(os.Args) > 2 || (len(os.Args) == 2 && (os.Args[1] == "-h" || os.Args[1] == "--help")) {
-		showUsage()
-		os.Exit(1)
-		return
-	}
-
-	// fetch all containers data
-	allImages, err := Docker.ListImages(docker.ListImagesOptions{All: true})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "docker-images: %s\n", err)
-		os.Exit(1)
-	}
-
-	if len(os.Args) == 2 {
-		// show images that have at least a partial pattern match
-		pattern := os.Args[1]
-
-		for _, image := range allImages {
-			for _, name := range getNamesOrIDs(&image) {
-				if strings.Contains(name, pattern) {
-					display(&image, name)
-				}
-			}
-		}
-	} else {
-		// show all images
-		for _, image := range allImages {
-			for _, name := range getNamesOrIDs(&image) {
-				display(&image, name)
-			}
-		}
-	}
+	images.Main()
 }
@@ -2,7 +2,7 @@
 export PATH="$PATH:/usr/local/go/bin"
 export GOPATH=~/goroot
 
-go get "github.com/gdm85/go-dockerclient" || exit $?
+go get "github.com/gdm85/go-dockerclient" "github.com/gdm85/goopt" || exit $?
 
 ## build without debug information
 go build -ldflags "-w -s"
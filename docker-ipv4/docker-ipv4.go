@@ -20,47 +20,9 @@ Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
 package main
 
 import (
-	"fmt"
-	"github.com/gdm85/go-dockerclient"
-	"os"
+	"github.com/gdm85/docker-cli-tools/internal/tools/ipv4"
 )
 
-var Docker *docker.Client
-
-func init() {
-	var err error
-	Docker, err = docker.NewClient("unix:///var/run/docker.sock")
-	if err != nil {
-		panic(err)
-	}
-}
-
-func showUsage() {
-	fmt.Fprintln(os.Stderr, "Usage: docker-ipv4 container1 [container2] [...] [containerN]")
-	fmt.Fprintln(os.Stderr, "docker-ipv4 is part of docker-cli-tools and licensed under GNU GPLv2")
-}
-
 func main() {
-	// if no arguments specified, show help and exit with failure
-	if len(os.Args) == 1 || (len(os.Args) == 2 && (os.Args[1] == "-h" || os.Args[1] == "--help")) {
-		showUsage()
-		os.Exit(1)
-		return
-	}
-
-	for _, containerId := range os.Args[1:] {
-		// pull new inspect data from API
-		container, err := Docker.InspectContainer(containerId)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "docker-ipv4: cannot find '%s': %s\n", containerId, err)
-			os.Exit(2)
-		}
-
-		if !container.State.Running {
-			fmt.Fprintf(os.Stderr, "docker-ipv4: container '%s' is not running\n", container.Name[1:])
-			os.Exit(2)
-		}
-
-		fmt.Println(container.NetworkSettings.IPAddress)
-	}
+	ipv4.Main()
 }
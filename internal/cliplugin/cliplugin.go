@@ -0,0 +1,136 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package cliplugin implements the Docker CLI plugin protocol, so that a
+// tool installed as ~/.docker/cli-plugins/docker-NAME runs as 'docker NAME'.
+package cliplugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MetadataSubcommand is invoked by the docker CLI to discover plugins
+	MetadataSubcommand = "docker-cli-plugin-metadata"
+	// set by the docker CLI when running a plugin
+	originalCommandEnv = "DOCKER_CLI_PLUGIN_ORIGINAL_CLI_COMMAND"
+
+	Vendor  = "gdm85"
+	Version = "0.1"
+	URL     = "https://github.com/gdm85/docker-cli-tools/"
+)
+
+// Metadata is the answer to MetadataSubcommand
+type Metadata struct {
+	SchemaVersion    string
+	Vendor           string
+	Version          string
+	ShortDescription string
+	URL              string
+}
+
+// PluginName returns the plugin name for an executable name, e.g. docker-hosts -> hosts;
+// the docker CLI accepts only lowercase alphanumeric plugin names
+func PluginName(executable string) string {
+	return strings.Replace(strings.TrimPrefix(filepath.Base(executable), "docker-"), "-", "", -1)
+}
+
+// docker CLI global options and whether they take a value
+var globalOptions = map[string]bool{
+	"--config":    true,
+	"-c":          true,
+	"--context":   true,
+	"-D":          false,
+	"--debug":     false,
+	"-H":          true,
+	"--host":      true,
+	"-l":          true,
+	"--log-level": true,
+	"--tls":       false,
+	"--tlscacert": true,
+	"--tlscert":   true,
+	"--tlskey":    true,
+	"--tlsverify": false,
+}
+
+// Handle must be called before parsing the command line: it answers the
+// metadata request of the docker CLI and, when running as a plugin, rewrites
+// os.Args into a regular invocation of the tool, keeping the connection
+// options given to the docker CLI
+func Handle(description string) {
+	if len(os.Args) == 2 && os.Args[1] == MetadataSubcommand {
+		json.NewEncoder(os.Stdout).Encode(Metadata{
+			SchemaVersion:    "0.1.0",
+			Vendor:           Vendor,
+			Version:          Version,
+			ShortDescription: description,
+			URL:              URL,
+		})
+		os.Exit(0)
+		return
+	}
+
+	if os.Getenv(originalCommandEnv) == "" {
+		return
+	}
+
+	// the docker CLI passes its own arguments: global options, plugin name, plugin arguments
+	name := PluginName(os.Args[0])
+	args := []string{os.Args[0]}
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		if arg == name {
+			os.Args = append(args, os.Args[i+1:]...)
+			return
+		}
+
+		option, value := arg, ""
+		hasValue := false
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 && strings.HasPrefix(arg, "--") {
+			option, value, hasValue = parts[0], parts[1], true
+		}
+		takesValue, known := globalOptions[option]
+		if !known {
+			// not a docker CLI invocation after all
+			return
+		}
+		if takesValue && !hasValue {
+			if i+1 == len(os.Args) {
+				return
+			}
+			i++
+			value = os.Args[i]
+		}
+
+		switch option {
+		case "--config":
+			os.Setenv("DOCKER_CONFIG", value)
+		case "-c", "--context":
+			args = append(args, "--context", value)
+		case "-H", "--host", "--tlscacert", "--tlscert", "--tlskey":
+			args = append(args, option, value)
+		case "--tls", "--tlsverify":
+			args = append(args, option)
+		}
+		// debug and log level options are ignored
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package dockerenv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// subset of ~/.docker/config.json used here
+type cliConfig struct {
+	CurrentContext string `json:"currentContext"`
+}
+
+// subset of ~/.docker/contexts/meta/<digest>/meta.json
+type contextMeta struct {
+	Name      string
+	Endpoints map[string]struct {
+		Host          string
+		SkipTLSVerify bool
+	}
+}
+
+// configDir returns the docker CLI configuration directory
+func configDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+
+	home := os.Getenv("HOME")
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// currentContext returns the context selected with 'docker context use', if any
+func currentContext() (string, error) {
+	fileName := filepath.Join(configDir(), "config.json")
+	bytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var config cliConfig
+	if err := json.Unmarshal(bytes, &config); err != nil {
+		return "", fmt.Errorf("cannot parse '%s': %s", fileName, err)
+	}
+
+	return config.CurrentContext, nil
+}
+
+// LoadContext reads the Docker endpoint of the named docker CLI context
+func LoadContext(name string) (*Endpoint, error) {
+	if name == "default" {
+		return withTLS(&Endpoint{Host: localEndpoint(), Context: name})
+	}
+
+	// the context store indexes contexts by the SHA-256 digest of their name
+	digest := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(digest[:])
+
+	fileName := filepath.Join(configDir(), "contexts", "meta", id, "meta.json")
+	bytes, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("context '%s' does not exist", name)
+		}
+		return nil, err
+	}
+
+	var meta contextMeta
+	if err := json.Unmarshal(bytes, &meta); err != nil {
+		return nil, fmt.Errorf("cannot parse context '%s': %s", name, err)
+	}
+
+	endpoint, ok := meta.Endpoints["docker"]
+	if !ok || endpoint.Host == "" {
+		return nil, fmt.Errorf("context '%s' has no Docker endpoint", name)
+	}
+
+	ep := &Endpoint{Host: endpoint.Host, Context: name}
+
+	// TLS material is optional and stored separately from metadata
+	tlsDir := filepath.Join(configDir(), "contexts", "tls", id, "docker")
+	for fileName, target := range map[string]*string{"ca.pem": &ep.TLSCACert, "cert.pem": &ep.TLSCert, "key.pem": &ep.TLSKey} {
+		path := filepath.Join(tlsDir, fileName)
+		if _, err := os.Stat(path); err == nil {
+			*target = path
+			ep.TLS = true
+		}
+	}
+
+	// without a CA the client skips verification of the daemon certificate
+	if endpoint.SkipTLSVerify {
+		ep.TLSCACert = ""
+	}
+	// a client certificate is usable only with its key, and optional when
+	// the daemon does not authenticate clients
+	if ep.TLSCert == "" || ep.TLSKey == "" {
+		ep.TLSCert, ep.TLSKey = "", ""
+	}
+
+	return ep, nil
+}
@@ -0,0 +1,287 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package dockerenv builds a Docker API client from the environment, the
+// docker CLI configuration/contexts and the connection command line options
+// shared by all docker-cli-tools.
+package dockerenv
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gdm85/go-dockerclient"
+	"github.com/gdm85/goopt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultEndpoint = "unix:///var/run/docker.sock"
+	defaultSocket   = "/var/run/docker.sock"
+)
+
+// Endpoint describes how to reach a Docker daemon
+type Endpoint struct {
+	Host      string
+	TLS       bool
+	TLSCACert string
+	TLSCert   string
+	TLSKey    string
+	// name of the docker CLI context this endpoint was loaded from, if any
+	Context string
+}
+
+// Name identifies the endpoint in the output of the tools
+func (ep *Endpoint) Name() string {
+	if ep.Context != "" {
+		return ep.Context
+	}
+	return ep.Host
+}
+
+var (
+	flagHost      *[]string
+	flagHostsFile *string
+	flagContext   *string
+	flagTLS       *bool
+	flagTLSVerify *bool
+	flagTLSCACert *string
+	flagTLSCert   *string
+	flagTLSKey    *string
+)
+
+// RegisterFlags adds the connection options to the command line parser;
+// it must be called before goopt.Parse
+func RegisterFlags() {
+	flagHost = goopt.Strings([]string{"-H", "--host"}, "HOST", "daemon socket to connect to (e.g. unix:///var/run/docker.sock or tcp://host:2376); can be repeated")
+	flagHostsFile = goopt.String([]string{"--hosts-file"}, "", "file listing the daemon sockets to connect to, one per line")
+	flagContext = goopt.String([]string{"--context"}, "", "name of the docker CLI context to use")
+	flagTLS = goopt.Flag([]string{"--tls"}, []string{}, "use TLS; implied by --tlsverify", "")
+	flagTLSVerify = goopt.Flag([]string{"--tlsverify"}, []string{}, "use TLS and verify the remote daemon", "")
+	flagTLSCACert = goopt.String([]string{"--tlscacert"}, "", "trust certificates signed only by this CA")
+	flagTLSCert = goopt.String([]string{"--tlscert"}, "", "path to TLS certificate file")
+	flagTLSKey = goopt.String([]string{"--tlskey"}, "", "path to TLS key file")
+}
+
+func flagValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// hosts returns the daemon sockets given with --host and --hosts-file
+func hosts() ([]string, error) {
+	var result []string
+	if flagHost != nil {
+		result = append(result, *flagHost...)
+	}
+
+	fileName := flagValue(flagHostsFile)
+	if fileName == "" {
+		return result, nil
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// empty lines and comments starting with '#' are skipped
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read '%s': %s", fileName, err)
+	}
+
+	return result, nil
+}
+
+// Resolve picks the daemon endpoint following the same precedence as the docker CLI:
+// --host, --context, DOCKER_HOST, DOCKER_CONTEXT, current context of the CLI configuration
+// and finally the local (or rootless) socket
+func Resolve() (*Endpoint, error) {
+	hosts, err := hosts()
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) > 1 {
+		return nil, fmt.Errorf("only one Docker host can be specified")
+	}
+	if len(hosts) == 1 {
+		return withTLS(&Endpoint{Host: hosts[0]})
+	}
+
+	if name := flagValue(flagContext); name != "" {
+		return LoadContext(name)
+	}
+
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return withTLS(&Endpoint{Host: host})
+	}
+
+	name := os.Getenv("DOCKER_CONTEXT")
+	if name == "" {
+		name, err = currentContext()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if name != "" && name != "default" {
+		return LoadContext(name)
+	}
+
+	return withTLS(&Endpoint{Host: localEndpoint()})
+}
+
+// Endpoints returns all the endpoints given with --host and --hosts-file, or
+// the single endpoint picked by Resolve when there are not several of them
+func Endpoints() ([]*Endpoint, error) {
+	hosts, err := hosts()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hosts) < 2 {
+		ep, err := Resolve()
+		if err != nil {
+			return nil, err
+		}
+		return []*Endpoint{ep}, nil
+	}
+
+	// TLS options apply to all the hosts
+	endpoints := make([]*Endpoint, len(hosts))
+	for i, host := range hosts {
+		endpoints[i], err = withTLS(&Endpoint{Host: host})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return endpoints, nil
+}
+
+// localEndpoint returns the system socket, or the rootless daemon socket when
+// only the latter exists
+func localEndpoint() string {
+	if _, err := os.Stat(defaultSocket); err == nil {
+		return DefaultEndpoint
+	}
+
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		rootless := filepath.Join(runtimeDir, "docker.sock")
+		if _, err := os.Stat(rootless); err == nil {
+			return "unix://" + rootless
+		}
+	}
+
+	return DefaultEndpoint
+}
+
+// withTLS completes the endpoint with TLS settings from command line options
+// and DOCKER_TLS_VERIFY/DOCKER_CERT_PATH
+func withTLS(ep *Endpoint) (*Endpoint, error) {
+	ep.TLSCACert = flagValue(flagTLSCACert)
+	ep.TLSCert = flagValue(flagTLSCert)
+	ep.TLSKey = flagValue(flagTLSKey)
+
+	verify := (flagTLSVerify != nil && *flagTLSVerify) || os.Getenv("DOCKER_TLS_VERIFY") != "" || ep.TLSCACert != ""
+	ep.TLS = verify || (flagTLS != nil && *flagTLS) || ep.TLSCert != "" || ep.TLSKey != ""
+	if !ep.TLS {
+		return ep, nil
+	}
+
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		certPath = configDir()
+	}
+	if ep.TLSCACert == "" && verify {
+		ep.TLSCACert = filepath.Join(certPath, "ca.pem")
+	}
+
+	// a client certificate is needed only by daemons authenticating clients,
+	// thus the default one is used only when present
+	defaultCert, defaultKey := filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem")
+	if ep.TLSCert == "" && ep.TLSKey == "" {
+		if exists(defaultCert) && exists(defaultKey) {
+			ep.TLSCert, ep.TLSKey = defaultCert, defaultKey
+		}
+	} else if ep.TLSCert == "" {
+		ep.TLSCert = defaultCert
+	} else if ep.TLSKey == "" {
+		ep.TLSKey = defaultKey
+	}
+
+	for _, fileName := range []string{ep.TLSCACert, ep.TLSCert, ep.TLSKey} {
+		// without a CA the client skips verification of the daemon certificate
+		if fileName == "" {
+			continue
+		}
+		if _, err := os.Stat(fileName); err != nil {
+			return nil, fmt.Errorf("TLS enabled for %s but cannot use '%s': %s", ep.Host, fileName, err)
+		}
+	}
+
+	return ep, nil
+}
+
+// exists returns true if fileName can be stat'ed
+func exists(fileName string) bool {
+	_, err := os.Stat(fileName)
+	return err == nil
+}
+
+// NewClient returns a client for the given endpoint
+func (ep *Endpoint) NewClient() (*docker.Client, error) {
+	if strings.HasPrefix(ep.Host, "ssh://") {
+		return nil, fmt.Errorf("ssh endpoints are not supported: %s", ep.Host)
+	}
+
+	var client *docker.Client
+	var err error
+	if ep.TLS {
+		client, err = docker.NewTLSClient(ep.Host, ep.TLSCert, ep.TLSKey, ep.TLSCACert)
+	} else {
+		client, err = docker.NewClient(ep.Host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot use Docker endpoint '%s': %s", ep.Host, err)
+	}
+
+	return client, nil
+}
+
+// NewClient resolves the endpoint and returns a client for it
+func NewClient() (*docker.Client, error) {
+	ep, err := Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	return ep.NewClient()
+}
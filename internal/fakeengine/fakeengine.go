@@ -0,0 +1,543 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package fakeengine implements a minimal in-process Docker Engine API server
+// answering from fixture files, so that the tools can be exercised without a
+// Docker daemon.
+//
+// The fixture directory layout is:
+//
+//	containers.json           list data, as returned by /containers/json?all=1
+//	inspect/<ID>.json         inspect data, as returned by /containers/<ID>/json
+//	images.json               list data, as returned by /images/json
+//	events.jsonl              one event per line, streamed by /events; lines appended
+//	                          later are streamed as well
+//	archive/<ID>/<path>       files served by /containers/<ID>/archive?path=<path>
+//	logs/<ID>.jsonl           log entries in the format of the json-file logging driver,
+//	                          served by /containers/<ID>/logs
+//
+// Requests changing containers (stop, kill, rm...) are answered like the
+// daemon would from the listed state of the container, without changing the
+// fixtures; they can be recorded with LogActions.
+package fakeengine
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API version prefix optionally used by clients, e.g. /v1.41/containers/json
+var rxVersion = regexp.MustCompile("^/v[0-9.]+/")
+
+type Server struct {
+	dir      string
+	listener net.Listener
+	server   *http.Server
+
+	mu       sync.Mutex
+	requests []string
+	// where requests changing containers are recorded, when not nil
+	actions io.Writer
+}
+
+// New returns a server answering from the fixtures found in dir
+func New(dir string) *Server {
+	s := &Server{dir: dir}
+	s.server = &http.Server{Handler: s}
+	return s
+}
+
+// Listen opens the listening socket, e.g. Listen("unix", "/tmp/docker.sock")
+func (s *Server) Listen(network, address string) error {
+	if network == "unix" {
+		os.Remove(address)
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	return nil
+}
+
+// Endpoint returns the address to use as DOCKER_HOST
+func (s *Server) Endpoint() string {
+	addr := s.listener.Addr()
+	if addr.Network() == "unix" {
+		return "unix://" + addr.String()
+	}
+	return "tcp://" + addr.String()
+}
+
+// Serve answers requests until Close is called
+func (s *Server) Serve() error {
+	err := s.server.Serve(s.listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// LogActions records the 'METHOD path?query' of requests changing containers
+// to w, one per line
+func (s *Server) LogActions(w io.Writer) {
+	s.mu.Lock()
+	s.actions = w
+	s.mu.Unlock()
+}
+
+// Requests returns the 'METHOD path' of all requests served so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := rxVersion.ReplaceAllString(r.URL.Path, "/")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+path)
+	if s.actions != nil && r.Method != "GET" && r.Method != "HEAD" {
+		fmt.Fprintf(s.actions, "%s %s?%s\n", r.Method, path, r.URL.RawQuery)
+	}
+	s.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case path == "/_ping":
+		fmt.Fprint(w, "OK")
+	case path == "/containers/json" && r.Method == "GET":
+		s.listContainers(w, r)
+	case path == "/images/json" && r.Method == "GET":
+		s.serveFile(w, "images.json")
+	case path == "/events" && r.Method == "GET":
+		s.events(w, r)
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "json" && r.Method == "GET":
+		container, ok := s.lookup(w, parts[1])
+		if ok {
+			s.serveFile(w, filepath.Join("inspect", container.ID+".json"))
+		}
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "archive" && (r.Method == "GET" || r.Method == "HEAD"):
+		container, ok := s.lookup(w, parts[1])
+		if ok {
+			s.archive(w, r, container.ID)
+		}
+	case len(parts) == 3 && parts[0] == "containers" && parts[2] == "logs" && r.Method == "GET":
+		container, ok := s.lookup(w, parts[1])
+		if ok {
+			s.logs(w, r, container.ID)
+		}
+	case len(parts) == 3 && parts[0] == "containers" && r.Method == "POST":
+		container, ok := s.lookup(w, parts[1])
+		if ok {
+			s.action(w, r, container, parts[1], parts[2])
+		}
+	case len(parts) == 2 && parts[0] == "containers" && r.Method == "DELETE":
+		container, ok := s.lookup(w, parts[1])
+		if ok {
+			s.remove(w, r, container, parts[1])
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("page not found: %s %s", r.Method, path))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, name string) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// listed container, as much as needed to look it up
+type listed struct {
+	ID    string `json:"Id"`
+	Names []string
+	State string
+}
+
+func (s *Server) containers() ([]json.RawMessage, []listed, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, "containers.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+	var parsed []listed
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, nil, err
+	}
+	return raw, parsed, nil
+}
+
+func (s *Server) listContainers(w http.ResponseWriter, r *http.Request) {
+	raw, parsed, err := s.containers()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	all := r.URL.Query().Get("all")
+	result := []json.RawMessage{}
+	for i, container := range parsed {
+		if all == "1" || all == "true" || container.State == "running" {
+			result = append(result, raw[i])
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// lookup finds a container by full ID, name or unique ID prefix, like the daemon does
+func (s *Server) lookup(w http.ResponseWriter, ref string) (*listed, bool) {
+	_, parsed, err := s.containers()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	var found *listed
+	for i := range parsed {
+		container := &parsed[i]
+		if container.ID == ref {
+			return container, true
+		}
+		for _, name := range container.Names {
+			if name == "/"+ref {
+				return container, true
+			}
+		}
+		if strings.HasPrefix(container.ID, ref) {
+			if found != nil {
+				writeError(w, http.StatusNotFound, "Multiple IDs found with provided prefix: "+ref)
+				return nil, false
+			}
+			found = container
+		}
+	}
+	if found == nil {
+		writeError(w, http.StatusNotFound, "No such container: "+ref)
+		return nil, false
+	}
+	return found, true
+}
+
+// events streams the fixture events, then keeps the stream open until the
+// client goes away or the 'until' timestamp is reached, streaming the lines
+// appended to events.jsonl in the meantime
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	fileName := filepath.Join(s.dir, "events.jsonl")
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// like the daemon, send headers even when there are no events yet
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	send := func(data []byte) {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			fmt.Fprintln(w, line)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	send(data)
+
+	if r.URL.Query().Get("until") != "" {
+		return
+	}
+
+	// only complete lines are sent
+	sent := bytes.LastIndexByte(data, '\n') + 1
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil || len(data) <= sent {
+			continue
+		}
+		end := bytes.LastIndexByte(data, '\n') + 1
+		if end <= sent {
+			continue
+		}
+		send(data[sent:end])
+		sent = end
+	}
+}
+
+// archive serves a single fixture file as a tar stream
+func (s *Server) archive(w http.ResponseWriter, r *http.Request, ID string) {
+	path := r.URL.Query().Get("path")
+	fileName := filepath.Join(s.dir, "archive", ID, filepath.FromSlash(path))
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Could not find the file %s in container %s", path, ID))
+		return
+	}
+
+	stat, _ := json.Marshal(map[string]interface{}{
+		"name":  filepath.Base(path),
+		"size":  len(content),
+		"mode":  0644,
+		"mtime": time.Unix(0, 0).UTC().Format(time.RFC3339),
+	})
+	w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+	w.Header().Set("Content-Type", "application/x-tar")
+	if r.Method == "HEAD" {
+		return
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: filepath.Base(path), Mode: 0644, Size: int64(len(content)), ModTime: time.Unix(0, 0)})
+	tw.Write(content)
+	tw.Close()
+	w.Write(buf.Bytes())
+}
+
+// isRunning tells whether the daemon considers a container with the listed
+// state as running
+func isRunning(state string) bool {
+	return state == "running" || state == "paused" || state == "restarting"
+}
+
+// action answers POST /containers/<ref>/<name>
+func (s *Server) action(w http.ResponseWriter, r *http.Request, container *listed, ref, name string) {
+	switch name {
+	case "stop":
+		if !isRunning(container.State) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	case "restart":
+	case "kill":
+		if !isRunning(container.State) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Cannot kill container: %s: Container %s is not running", ref, container.ID))
+			return
+		}
+	case "pause":
+		if container.State == "paused" {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Container %s is already paused", container.ID))
+			return
+		}
+		if !isRunning(container.State) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Container %s is not running", container.ID))
+			return
+		}
+	case "unpause":
+		if container.State != "paused" {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Container %s is not paused", container.ID))
+			return
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("page not found: %s %s", r.Method, r.URL.Path))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// remove answers DELETE /containers/<ref>
+func (s *Server) remove(w http.ResponseWriter, r *http.Request, container *listed, ref string) {
+	force := r.URL.Query().Get("force")
+	if isRunning(container.State) && force != "1" && force != "true" {
+		writeError(w, http.StatusConflict, fmt.Sprintf("You cannot remove a running container %s. Stop the container before attempting removal or force remove", container.ID))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxFrame is the largest payload of a frame of the logs stream
+const maxFrame = 32
+
+// logEntry is a line of a json-file log
+type logEntry struct {
+	Log    string `json:"log"`
+	Stream string `json:"stream"`
+	Time   string `json:"time"`
+}
+
+// parseTimestamp parses the since and until parameters of the logs endpoint,
+// UNIX times with optional fractional nanoseconds
+func parseTimestamp(value string) (time.Time, error) {
+	parts := strings.SplitN(value, ".", 2)
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nsec int64
+	if len(parts) == 2 {
+		nsec, err = strconv.ParseInt((parts[1] + "000000000")[:9], 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// logs serves the log fixture of a container, multiplexed with the stream
+// framing of the daemon unless the container has a TTY
+func (s *Server) logs(w http.ResponseWriter, r *http.Request, ID string) {
+	query := r.URL.Query()
+	enabled := func(name string) bool {
+		return query.Get(name) == "1" || query.Get(name) == "true"
+	}
+	var since, until time.Time
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		value := query.Get(p.name)
+		if value == "" || value == "0" {
+			continue
+		}
+		t, err := parseTimestamp(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid value for %s: %s", p.name, value))
+			return
+		}
+		*p.t = t
+	}
+	if !enabled("stdout") && !enabled("stderr") {
+		writeError(w, http.StatusBadRequest, "Bad parameters: you must choose at least one stream")
+		return
+	}
+
+	var inspect struct {
+		Config struct {
+			Tty bool
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, "inspect", ID+".json"))
+	if err == nil {
+		json.Unmarshal(data, &inspect)
+	}
+
+	var entries []logEntry
+	f, err := os.Open(filepath.Join(s.dir, "logs", ID+".jsonl"))
+	if err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry logEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			t, err := time.Parse(time.RFC3339Nano, entry.Time)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if !enabled(entry.Stream) || (!since.IsZero() && t.Before(since)) || (!until.IsZero() && t.After(until)) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	if tail, err := strconv.Atoi(query.Get("tail")); err == nil && tail >= 0 && tail < len(entries) {
+		entries = entries[len(entries)-tail:]
+	}
+
+	if inspect.Config.Tty {
+		w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+	}
+	for _, entry := range entries {
+		payload := entry.Log
+		if enabled("timestamps") {
+			payload = entry.Time + " " + payload
+		}
+		if inspect.Config.Tty {
+			io.WriteString(w, payload)
+			continue
+		}
+
+		// like the daemon does for large writes, lines are split across
+		// frames, here short ones to exercise demultiplexing
+		for len(payload) != 0 {
+			size := len(payload)
+			if size > maxFrame {
+				size = maxFrame
+			}
+			header := make([]byte, 8)
+			header[0] = 1
+			if entry.Stream == "stderr" {
+				header[0] = 2
+			}
+			binary.BigEndian.PutUint32(header[4:], uint32(size))
+			w.Write(header)
+			io.WriteString(w, payload[:size])
+			payload = payload[size:]
+		}
+	}
+}
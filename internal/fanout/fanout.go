@@ -0,0 +1,178 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package fanout runs the query of a tool against one or more Docker daemons
+// concurrently, then writes the merged results in the order the daemons were
+// specified and reports failures per daemon.
+package fanout
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/go-dockerclient"
+	"os"
+	"sync"
+)
+
+// Error is a failure with the exit status it maps to; other errors map to 1
+type Error struct {
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Errorf returns an *Error with the given exit status
+func Errorf(status int, format string, a ...interface{}) error {
+	return &Error{Status: status, Err: fmt.Errorf(format, a...)}
+}
+
+// QueryFunc lists the entries of a single daemon; host is the name to store
+// in the Host field of entries, empty when only one daemon is queried.
+// Entries returned along with an error are still written.
+type QueryFunc func(host string, client *docker.Client) ([]interface{}, error)
+
+type result struct {
+	entries []interface{}
+	err     error
+}
+
+// query runs query against all endpoints concurrently
+func query(endpoints []*dockerenv.Endpoint, query QueryFunc) []result {
+	multiple := len(endpoints) > 1
+	results := make([]result, len(endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(r *result, ep *dockerenv.Endpoint) {
+			defer wg.Done()
+
+			client, err := ep.NewClient()
+			if err != nil {
+				r.err = err
+				return
+			}
+
+			var host string
+			if multiple {
+				host = ep.Name()
+			}
+			r.entries, r.err = query(host, client)
+		}(&results[i], ep)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// report prints the failure of the query of endpoints[i], if any, and returns
+// its exit status
+func report(tool string, endpoints []*dockerenv.Endpoint, i int, err error) int {
+	if err == nil {
+		return 0
+	}
+	if len(endpoints) > 1 {
+		fmt.Fprintf(os.Stderr, "%s: %s: %s\n", tool, endpoints[i].Name(), err)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", tool, err)
+	}
+
+	if e, ok := err.(*Error); ok {
+		return e.Status
+	}
+	return 1
+}
+
+// Collect queries all endpoints concurrently and returns their entries in
+// the order of the endpoints; failures are reported on stderr, prefixed by the
+// tool name and the endpoint when there are several. The returned exit status
+// is 0 if no query failed
+func Collect(tool string, endpoints []*dockerenv.Endpoint, queryFunc QueryFunc) ([]interface{}, int) {
+	var entries []interface{}
+	status := 0
+	for i, r := range query(endpoints, queryFunc) {
+		entries = append(entries, r.entries...)
+		if errStatus := report(tool, endpoints, i, r.err); errStatus > status {
+			status = errStatus
+		}
+	}
+
+	return entries, status
+}
+
+// Run queries all endpoints like Collect and writes the entries of each one
+// to out before reporting its failure. It returns the exit status, 0 if no
+// query failed
+func Run(tool string, endpoints []*dockerenv.Endpoint, out *output.Writer, queryFunc QueryFunc) int {
+	if len(endpoints) > 1 {
+		out.ShowHost()
+	}
+
+	status := 0
+	for i, r := range query(endpoints, queryFunc) {
+		for _, entry := range r.entries {
+			err := out.Write(entry)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", tool, err)
+				return 1
+			}
+		}
+
+		if errStatus := report(tool, endpoints, i, r.err); errStatus > status {
+			status = errStatus
+		}
+	}
+
+	err := out.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", tool, err)
+		return 1
+	}
+
+	return status
+}
+
+// RunSorted is like Run, except that the entries of all endpoints are passed
+// to sortFunc once collected, and then written in the resulting order
+func RunSorted(tool string, endpoints []*dockerenv.Endpoint, out *output.Writer, queryFunc QueryFunc, sortFunc func(entries []interface{})) int {
+	if len(endpoints) > 1 {
+		out.ShowHost()
+	}
+
+	entries, status := Collect(tool, endpoints, queryFunc)
+	sortFunc(entries)
+	for _, entry := range entries {
+		err := out.Write(entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", tool, err)
+			return 1
+		}
+	}
+
+	err := out.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", tool, err)
+		return 1
+	}
+
+	return status
+}
@@ -0,0 +1,143 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package fanout
+
+import (
+	"errors"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/go-dockerclient"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// outcome is what the fake query returns for a daemon
+type outcome struct {
+	entries []interface{}
+	err     error
+}
+
+// collect runs Collect against a daemon per outcome, with the daemon host as
+// key, and returns what it wrote to stderr besides its results
+func collect(t *testing.T, hosts []string, outcomes map[string]outcome) ([]interface{}, int, string) {
+	var endpoints []*dockerenv.Endpoint
+	for _, host := range hosts {
+		endpoints = append(endpoints, &dockerenv.Endpoint{Host: host})
+	}
+
+	f, err := ioutil.TempFile("", "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	stderr := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = stderr }()
+
+	entries, status := Collect("docker-test", endpoints, func(host string, client *docker.Client) ([]interface{}, error) {
+		for _, ep := range endpoints {
+			if host == ep.Host || host == "" && len(endpoints) == 1 {
+				o := outcomes[ep.Host]
+				return o.entries, o.err
+			}
+		}
+		t.Errorf("unexpected host %q", host)
+		return nil, nil
+	})
+
+	os.Stderr = stderr
+	printed, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries, status, string(printed)
+}
+
+const (
+	a = "unix:///a.sock"
+	b = "unix:///b.sock"
+	c = "unix:///c.sock"
+)
+
+func TestCollectStatus(t *testing.T) {
+	warning := Errorf(3, "1 of 2 containers could not be inspected")
+	failure := errors.New("cannot connect")
+	tests := []struct {
+		name     string
+		outcomes map[string]outcome
+		want     int
+	}{
+		{"success", map[string]outcome{a: {}, b: {}, c: {}}, 0},
+		{"warning", map[string]outcome{a: {}, b: {err: warning}, c: {}}, 3},
+		{"failure", map[string]outcome{a: {err: failure}, b: {}, c: {}}, 1},
+		{"highest status", map[string]outcome{a: {err: failure}, b: {err: warning}, c: {err: Errorf(2, "bad")}}, 3},
+	}
+	for _, test := range tests {
+		_, status, _ := collect(t, []string{a, b, c}, test.outcomes)
+		if status != test.want {
+			t.Errorf("%s: got status %d, want %d", test.name, status, test.want)
+		}
+	}
+}
+
+func TestCollectEntries(t *testing.T) {
+	outcomes := map[string]outcome{
+		a: {entries: []interface{}{"a1", "a2"}},
+		b: {err: errors.New("cannot connect")},
+		c: {entries: []interface{}{"c1"}, err: Errorf(3, "partial")},
+	}
+	entries, status, printed := collect(t, []string{c, a, b}, outcomes)
+
+	// entries returned along with an error are kept, in the order of endpoints
+	if want := []interface{}{"c1", "a1", "a2"}; !reflect.DeepEqual(entries, want) {
+		t.Errorf("got entries %v, want %v", entries, want)
+	}
+	if status != 3 {
+		t.Errorf("got status %d, want 3", status)
+	}
+	want := "docker-test: unix:///c.sock: partial\ndocker-test: unix:///b.sock: cannot connect\n"
+	if printed != want {
+		t.Errorf("got stderr %q, want %q", printed, want)
+	}
+}
+
+func TestCollectSingle(t *testing.T) {
+	_, status, printed := collect(t, []string{a}, map[string]outcome{a: {err: errors.New("cannot connect")}})
+	if status != 1 {
+		t.Errorf("got status %d, want 1", status)
+	}
+	// the endpoint is not named when it is the only one
+	if printed != "docker-test: cannot connect\n" {
+		t.Errorf("got stderr %q", printed)
+	}
+}
+
+func TestCollectClientError(t *testing.T) {
+	_, status, printed := collect(t, []string{a, "ssh://host"}, map[string]outcome{a: {}})
+	if status != 1 {
+		t.Errorf("got status %d, want 1", status)
+	}
+	if !strings.HasPrefix(printed, "docker-test: ssh://host: ssh endpoints are not supported") {
+		t.Errorf("got stderr %q", printed)
+	}
+}
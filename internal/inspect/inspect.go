@@ -0,0 +1,131 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package inspect provides a goroutine-safe cache of container inspect data,
+// filled on demand or in advance through a bounded pool of workers.
+package inspect
+
+import (
+	"github.com/gdm85/go-dockerclient"
+	"sync"
+)
+
+// DefaultParallel is the default amount of concurrent inspect requests
+const DefaultParallel = 8
+
+// Inspector is the subset of the Docker client used by the cache
+type Inspector interface {
+	InspectContainer(id string) (*docker.Container, error)
+}
+
+type entry struct {
+	done chan struct{}
+	data *docker.Container
+	err  error
+}
+
+type Cache struct {
+	client  Inspector
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+func NewCache(client Inspector) *Cache {
+	return &Cache{client: client, entries: map[string]*entry{}}
+}
+
+// Get returns inspect data of the container, pulling it from the API only once;
+// concurrent callers asking for the same ID wait for the same request
+func (c *Cache) Get(ID string) (*docker.Container, error) {
+	c.mu.Lock()
+	e, ok := c.entries[ID]
+	if ok {
+		c.mu.Unlock()
+		<-e.done
+		return e.data, e.err
+	}
+	e = &entry{done: make(chan struct{})}
+	c.entries[ID] = e
+	c.mu.Unlock()
+
+	e.data, e.err = c.client.InspectContainer(ID)
+	if e.err == nil {
+		// always fix the name leading slash
+		e.data.Name = e.data.Name[1:]
+	}
+	close(e.done)
+
+	return e.data, e.err
+}
+
+// Prefetch pulls inspect data of all specified containers using at most
+// parallel concurrent requests; errors are reported later by Get
+func (c *Cache) Prefetch(IDs []string, parallel int) {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ID := range work {
+				c.Get(ID)
+			}
+		}()
+	}
+
+	for _, ID := range IDs {
+		work <- ID
+	}
+	close(work)
+
+	wg.Wait()
+}
+
+// CompleteNames fills the names of list data entries that have none by
+// inspecting only those containers
+func (c *Cache) CompleteNames(containers []docker.APIContainers, parallel int) error {
+	var missing []string
+	for _, container := range containers {
+		if len(container.Names) == 0 {
+			missing = append(missing, container.ID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	c.Prefetch(missing, parallel)
+
+	for i := range containers {
+		if len(containers[i].Names) != 0 {
+			continue
+		}
+		inspectData, err := c.Get(containers[i].ID)
+		if err != nil {
+			return err
+		}
+		containers[i].Names = []string{"/" + inspectData.Name}
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package inspect
+
+import (
+	"errors"
+	"github.com/gdm85/go-dockerclient"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeInspector counts the requests per ID and the most requests running at
+// once; when release is not nil requests wait for it to be closed
+type fakeInspector struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	running  int
+	most     int
+	started  chan string
+	release  chan struct{}
+	failures map[string]error
+}
+
+func newFakeInspector() *fakeInspector {
+	return &fakeInspector{calls: map[string]int{}, failures: map[string]error{}}
+}
+
+func (f *fakeInspector) InspectContainer(id string) (*docker.Container, error) {
+	f.mu.Lock()
+	f.calls[id]++
+	f.running++
+	if f.running > f.most {
+		f.most = f.running
+	}
+	err := f.failures[id]
+	f.mu.Unlock()
+
+	if f.started != nil {
+		f.started <- id
+	}
+	if f.release != nil {
+		<-f.release
+	}
+
+	f.mu.Lock()
+	f.running--
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &docker.Container{ID: id, Name: "/name-" + id}, nil
+}
+
+func TestGetInFlight(t *testing.T) {
+	client := newFakeInspector()
+	client.started = make(chan string, 1)
+	client.release = make(chan struct{})
+	cache := NewCache(client)
+
+	const callers = 8
+	results := make(chan *docker.Container, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			data, err := cache.Get("a")
+			if err != nil {
+				t.Error(err)
+			}
+			results <- data
+		}()
+	}
+
+	// callers arriving while the request is in flight wait for it
+	<-client.started
+	close(client.release)
+
+	first := <-results
+	for i := 1; i < callers; i++ {
+		if data := <-results; data != first {
+			t.Errorf("caller %d got different inspect data", i)
+		}
+	}
+	if client.calls["a"] != 1 {
+		t.Errorf("got %d requests, want 1", client.calls["a"])
+	}
+	if first.Name != "name-a" {
+		t.Errorf("got name %q, want the leading slash stripped", first.Name)
+	}
+}
+
+func TestGetError(t *testing.T) {
+	client := newFakeInspector()
+	client.failures["a"] = errors.New("boom")
+	cache := NewCache(client)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get("a"); err == nil || err.Error() != "boom" {
+			t.Errorf("got error %v, want boom", err)
+		}
+	}
+	if client.calls["a"] != 1 {
+		t.Errorf("got %d requests, want the error to be cached", client.calls["a"])
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	for _, parallel := range []int{0, 1, 3} {
+		client := newFakeInspector()
+		cache := NewCache(client)
+
+		IDs := []string{"a", "b", "c", "a", "d", "e", "b", "f"}
+		cache.Prefetch(IDs, parallel)
+		for _, ID := range IDs {
+			if _, err := cache.Get(ID); err != nil {
+				t.Error(err)
+			}
+		}
+
+		want := map[string]int{"a": 1, "b": 1, "c": 1, "d": 1, "e": 1, "f": 1}
+		if !reflect.DeepEqual(client.calls, want) {
+			t.Errorf("parallel %d: got requests %v, want %v", parallel, client.calls, want)
+		}
+		limit := parallel
+		if limit < 1 {
+			limit = 1
+		}
+		if client.most > limit {
+			t.Errorf("parallel %d: got %d concurrent requests", parallel, client.most)
+		}
+	}
+}
+
+func TestPrefetchParallel(t *testing.T) {
+	client := newFakeInspector()
+	client.started = make(chan string)
+	client.release = make(chan struct{})
+	cache := NewCache(client)
+
+	done := make(chan struct{})
+	go func() {
+		cache.Prefetch([]string{"a", "b", "c", "d"}, 2)
+		close(done)
+	}()
+
+	// both workers are busy before any request completes
+	<-client.started
+	<-client.started
+	close(client.release)
+	for i := 0; i < 2; i++ {
+		<-client.started
+	}
+	<-done
+
+	if client.most != 2 {
+		t.Errorf("got at most %d concurrent requests, want 2", client.most)
+	}
+}
+
+func TestCompleteNames(t *testing.T) {
+	client := newFakeInspector()
+	cache := NewCache(client)
+
+	containers := []docker.APIContainers{
+		{ID: "a", Names: []string{"/listed"}},
+		{ID: "b"},
+	}
+	if err := cache.CompleteNames(containers, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(containers[0].Names, []string{"/listed"}) || !reflect.DeepEqual(containers[1].Names, []string{"/name-b"}) {
+		t.Errorf("got names %v and %v", containers[0].Names, containers[1].Names)
+	}
+	if client.calls["a"] != 0 {
+		t.Errorf("named container inspected")
+	}
+
+	client.failures["c"] = errors.New("boom")
+	if err := cache.CompleteNames([]docker.APIContainers{{ID: "c"}}, 2); err == nil {
+		t.Errorf("got no error for a container that cannot be inspected")
+	}
+}
@@ -0,0 +1,310 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package output renders the entries listed by the tools either in their
+// classic plain format or, as selected on the command line, through a Go
+// template, as JSON or as CSV.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gdm85/goopt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+const (
+	modePlain = iota
+	modeTemplate
+	modeJSON
+	modeJSONLines
+	modeCSV
+)
+
+var (
+	flagFormat    *string
+	flagJSON      *bool
+	flagJSONLines *bool
+	flagCSV       *bool
+	flagNoHeader  *bool
+)
+
+// RegisterFlags adds the output options to the command line parser, listing
+// the available fields in the help text; it must be called before goopt.Parse
+func RegisterFlags(fields []string) {
+	flagFormat = goopt.String([]string{"--format"}, "", "Go template applied to each entry, with fields ."+strings.Join(fields, " .")+" and functions json, join, truncate, upper, lower")
+	flagJSON = goopt.Flag([]string{"--json"}, []string{}, "output a JSON array of objects", "")
+	flagJSONLines = goopt.Flag([]string{"--jsonl"}, []string{}, "output one JSON object per line", "")
+	flagCSV = goopt.Flag([]string{"--csv"}, []string{}, "output comma-separated values", "")
+	flagNoHeader = goopt.Flag([]string{"--no-header"}, []string{}, "do not print the header line", "")
+}
+
+// Funcs are the helper functions available to --format templates
+var Funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": strings.Join,
+	// truncate counts characters, not bytes
+	"truncate": func(s string, n int) string {
+		runes := []rune(s)
+		if n < 0 {
+			n = 0
+		}
+		if len(runes) > n {
+			return string(runes[:n])
+		}
+		return s
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Writer renders entries, which are structs whose exported fields are the
+// documented output fields of a tool
+type Writer struct {
+	out    io.Writer
+	mode   int
+	fields []string
+	plain  func(entry interface{}) string
+	// renders an entry as cells of a table aligned on Flush, instead of plain
+	cells  func(entry interface{}) []string
+	titles []string
+	rows   [][]string
+	tmpl   *template.Template
+	csv    *csv.Writer
+	header bool
+	// entries carry the name of the daemon they come from
+	host bool
+	// entries kept for the JSON array, which is written on Flush
+	entries []interface{}
+}
+
+// NewWriter returns a writer honoring the output options; plain renders an
+// entry (without trailing newline) when none is selected
+func NewWriter(fields []string, plain func(entry interface{}) string) (*Writer, error) {
+	w := &Writer{out: os.Stdout, fields: fields, plain: plain, header: flagNoHeader == nil || !*flagNoHeader}
+
+	selected := 0
+	if flagFormat != nil && *flagFormat != "" {
+		tmpl, err := template.New("format").Funcs(Funcs).Parse(*flagFormat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --format template: %s", err)
+		}
+		w.tmpl = tmpl
+		w.mode = modeTemplate
+		selected++
+	}
+	if flagJSON != nil && *flagJSON {
+		w.mode = modeJSON
+		w.entries = []interface{}{}
+		selected++
+	}
+	if flagJSONLines != nil && *flagJSONLines {
+		w.mode = modeJSONLines
+		selected++
+	}
+	if flagCSV != nil && *flagCSV {
+		w.mode = modeCSV
+		w.csv = csv.NewWriter(w.out)
+		selected++
+	}
+	if selected > 1 {
+		return nil, fmt.Errorf("only one of --format, --json, --jsonl and --csv can be specified")
+	}
+
+	return w, nil
+}
+
+// NewTableWriter returns a writer like NewWriter, except that when no output
+// option is selected entries are rendered as the cells of a table, whose
+// columns are as wide as their widest cell, below a header line with the
+// given titles unless --no-header is specified or there are no entries
+func NewTableWriter(fields []string, titles []string, cells func(entry interface{}) []string) (*Writer, error) {
+	w, err := NewWriter(fields, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.cells = cells
+	w.titles = titles
+	return w, nil
+}
+
+// Align pads the cells of rows to the width of the widest cell of each
+// column and joins them with two spaces; the last cell is not padded
+func Align(rows [][]string) []string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		var b strings.Builder
+		for j, cell := range row {
+			if j == len(row)-1 {
+				b.WriteString(cell)
+				break
+			}
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell)+2))
+		}
+		lines[i] = b.String()
+	}
+	return lines
+}
+
+// ShowHost adds the Host field of entries to the output, as first column of
+// the plain and CSV formats; it is used when querying several daemons
+func (w *Writer) ShowHost() {
+	if w.host {
+		return
+	}
+	w.host = true
+	w.fields = append([]string{"Host"}, w.fields...)
+}
+
+// Structured returns true when entries are not rendered in the plain format
+func (w *Writer) Structured() bool {
+	return w.mode != modePlain
+}
+
+// Header returns true unless --no-header was specified
+func (w *Writer) Header() bool {
+	return w.header
+}
+
+// Write renders an entry; JSON arrays are complete only after Flush
+func (w *Writer) Write(entry interface{}) error {
+	switch w.mode {
+	case modeTemplate:
+		err := w.tmpl.Execute(w.out, entry)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w.out)
+		return err
+	case modeJSON:
+		w.entries = append(w.entries, entry)
+		return nil
+	case modeJSONLines:
+		return json.NewEncoder(w.out).Encode(entry)
+	case modeCSV:
+		if w.header {
+			w.header = false
+			err := w.csv.Write(w.fields)
+			if err != nil {
+				return err
+			}
+		}
+		return w.csv.Write(values(entry, w.fields))
+	}
+
+	if w.cells != nil {
+		row := w.cells(entry)
+		if w.host {
+			row = append(values(entry, []string{"Host"}), row...)
+		}
+		w.rows = append(w.rows, row)
+		return nil
+	}
+
+	line := w.plain(entry)
+	if w.host {
+		line = fmt.Sprintf("%-24s\t%s", values(entry, []string{"Host"})[0], line)
+	}
+	_, err := fmt.Fprintln(w.out, line)
+	return err
+}
+
+// Flush completes the output
+func (w *Writer) Flush() error {
+	switch w.mode {
+	case modeJSON:
+		b, err := json.MarshalIndent(w.entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "%s\n", b)
+		return err
+	case modeCSV:
+		// header is written even when there are no entries
+		if w.header {
+			w.header = false
+			w.csv.Write(w.fields)
+		}
+		w.csv.Flush()
+		return w.csv.Error()
+	case modePlain:
+		// unlike with CSV, an empty table has no header
+		rows := w.rows
+		if len(rows) != 0 && w.header {
+			w.header = false
+			titles := w.titles
+			if w.host {
+				titles = append([]string{"HOST"}, titles...)
+			}
+			rows = append([][]string{titles}, rows...)
+		}
+		for _, line := range Align(rows) {
+			_, err := fmt.Fprintln(w.out, line)
+			if err != nil {
+				return err
+			}
+		}
+		w.rows = nil
+	}
+	return nil
+}
+
+// values returns the named fields of entry as strings
+func values(entry interface{}, fields []string) []string {
+	v := reflect.Indirect(reflect.ValueOf(entry))
+	result := make([]string, len(fields))
+	for i, name := range fields {
+		field := v.FieldByName(name)
+		if !field.IsValid() {
+			continue
+		}
+		switch value := field.Interface().(type) {
+		case []string:
+			result[i] = strings.Join(value, ",")
+		case map[string]string:
+			b, _ := json.Marshal(value)
+			result[i] = string(b)
+		default:
+			result[i] = fmt.Sprint(value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package output
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{{truncate .Name 4}}", "café"},
+		{"{{truncate .Name 6}}", "café-ü"},
+		{"{{truncate .Name 20}}", "café-über"},
+		{"{{truncate .Name 0}}", ""},
+		{"{{truncate .Name -1}}", ""},
+		{"{{truncate .Image 3}}|{{upper (truncate .Image 5)}}", "日本語|日本語:Ü"},
+	}
+	entry := struct{ Name, Image string }{"café-über", "日本語:ü1"}
+	for _, test := range tests {
+		tmpl, err := template.New("format").Funcs(Funcs).Parse(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, entry); err != nil {
+			t.Errorf("%s: %s", test.format, err)
+			continue
+		}
+		if b.String() != test.want {
+			t.Errorf("%s: got %q, want %q", test.format, b.String(), test.want)
+		}
+	}
+}
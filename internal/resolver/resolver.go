@@ -0,0 +1,252 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package resolver turns container patterns given on the command line into
+// containers, with the same semantics for all docker-cli-tools.
+//
+// A pattern is tried, in order, as:
+//   - 're:REGEX', a regular expression matched against names
+//   - 'label:KEY' or 'label:KEY=VALUE', a label selector
+//   - 'glob:GLOB', a shell glob matched against names
+//   - a full container ID
+//   - an exact container name
+//   - a regular expression matched against names
+//   - when no name matches, a container ID prefix of any length, which must
+//     be unambiguous
+//
+// Options may ignore letter case and require regular expressions to match
+// whole names, like the -i and -x options of grep.
+package resolver
+
+import (
+	"fmt"
+	"github.com/gdm85/go-dockerclient"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// AmbiguousError is returned when a pattern that must identify a single container matches several
+type AmbiguousError struct {
+	Pattern    string
+	Candidates []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("ambiguous pattern '%s' matches %d containers: %s", e.Pattern, len(e.Candidates), strings.Join(e.Candidates, ", "))
+}
+
+// Options change how patterns match containers
+type Options struct {
+	// letter case is ignored in patterns, names and label values
+	IgnoreCase bool
+	// regular expressions must match whole names, and IDs are not matched
+	// by prefix
+	WholeName bool
+}
+
+type Resolver struct {
+	containers []docker.APIContainers
+	options    Options
+}
+
+// New returns a resolver over the specified list data, usually from ListContainers(All: true)
+func New(containers []docker.APIContainers) *Resolver {
+	return &Resolver{containers: containers}
+}
+
+// NewWithOptions returns a resolver like New, matching patterns as specified by options
+func NewWithOptions(containers []docker.APIContainers, options Options) *Resolver {
+	return &Resolver{containers: containers, options: options}
+}
+
+// Name returns the container name without leading slash, ignoring the
+// names given to it by links of other containers
+func Name(container *docker.APIContainers) string {
+	for _, name := range container.Names {
+		if strings.Count(name, "/") == 1 {
+			return name[1:]
+		}
+	}
+	return ""
+}
+
+// Prefixed returns true if pattern uses the 're:', 'label:' or 'glob:' syntax
+func Prefixed(pattern string) bool {
+	return strings.HasPrefix(pattern, "re:") || strings.HasPrefix(pattern, "label:") || strings.HasPrefix(pattern, "glob:")
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns the containers matching pattern, in list order;
+// no match is not an error
+func (r *Resolver) Resolve(pattern string) ([]*docker.APIContainers, error) {
+	if len(pattern) == 0 {
+		return nil, fmt.Errorf("empty pattern specified")
+	}
+
+	if strings.HasPrefix(pattern, "re:") {
+		rx, err := r.compile(pattern[3:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile regex pattern '%s': %s", pattern[3:], err)
+		}
+		return r.filter(func(container *docker.APIContainers) bool {
+			return rx.MatchString(Name(container))
+		}), nil
+	}
+
+	if strings.HasPrefix(pattern, "glob:") {
+		glob := pattern[5:]
+		// validate the glob once, since path.Match reports errors lazily
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern '%s': %s", glob, err)
+		}
+		return r.filter(func(container *docker.APIContainers) bool {
+			name := Name(container)
+			if r.options.IgnoreCase {
+				matched, _ := path.Match(strings.ToLower(glob), strings.ToLower(name))
+				return matched
+			}
+			matched, _ := path.Match(glob, name)
+			return matched
+		}), nil
+	}
+
+	if strings.HasPrefix(pattern, "label:") {
+		parts := strings.SplitN(pattern[6:], "=", 2)
+		if len(parts[0]) == 0 {
+			return nil, fmt.Errorf("empty label name in pattern '%s'", pattern)
+		}
+		return r.filter(func(container *docker.APIContainers) bool {
+			value, ok := container.Labels[parts[0]]
+			if !ok {
+				return false
+			}
+			return len(parts) == 1 || r.equal(value, parts[1])
+		}), nil
+	}
+
+	// full ID and exact name identify a single container
+	for i := range r.containers {
+		container := &r.containers[i]
+		if r.equal(container.ID, pattern) || r.equal(Name(container), pattern) {
+			return []*docker.APIContainers{container}, nil
+		}
+	}
+
+	matching, err := r.matchNames(pattern)
+	if err != nil {
+		return nil, err
+	}
+	// names win over ID prefixes, so that short patterns like 'db' keep
+	// matching names even when an ID starts with them
+	prefix := pattern
+	if r.options.IgnoreCase {
+		prefix = strings.ToLower(pattern)
+	}
+	if len(matching) != 0 || !isHex(prefix) || r.options.WholeName {
+		return matching, nil
+	}
+
+	matching = r.filter(func(container *docker.APIContainers) bool {
+		return strings.HasPrefix(container.ID, prefix)
+	})
+	if len(matching) > 1 {
+		err := &AmbiguousError{Pattern: pattern}
+		for _, container := range matching {
+			err.Candidates = append(err.Candidates, fmt.Sprintf("%s (%s)", Name(container), container.ID))
+		}
+		return nil, err
+	}
+	return matching, nil
+}
+
+// matchNames returns the containers with a name matching pattern as a
+// regular expression
+func (r *Resolver) matchNames(pattern string) ([]*docker.APIContainers, error) {
+	rx, err := r.compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile regex pattern '%s': %s", pattern, err)
+	}
+	return r.filter(func(container *docker.APIContainers) bool {
+		return rx.MatchString(Name(container))
+	}), nil
+}
+
+// compile compiles a regular expression matched against names, honoring
+// the options
+func (r *Resolver) compile(expr string) (*regexp.Regexp, error) {
+	if r.options.WholeName {
+		expr = "^(?:" + expr + ")$"
+	}
+	if r.options.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// equal compares a name, ID or label value with a pattern, honoring the options
+func (r *Resolver) equal(value, pattern string) bool {
+	if r.options.IgnoreCase {
+		return strings.EqualFold(value, pattern)
+	}
+	return value == pattern
+}
+
+// ResolveAll returns the containers matching any of the patterns, each container once
+func (r *Resolver) ResolveAll(patterns []string) ([]*docker.APIContainers, error) {
+	var result []*docker.APIContainers
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matching, err := r.Resolve(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range matching {
+			if !seen[container.ID] {
+				seen[container.ID] = true
+				result = append(result, container)
+			}
+		}
+	}
+	return result, nil
+}
+
+// filter returns containers with a name that satisfy match
+func (r *Resolver) filter(match func(*docker.APIContainers) bool) []*docker.APIContainers {
+	var result []*docker.APIContainers
+	for i := range r.containers {
+		container := &r.containers[i]
+		// ignore containers without a name
+		if Name(container) == "" {
+			continue
+		}
+		if match(container) {
+			result = append(result, container)
+		}
+	}
+	return result
+}
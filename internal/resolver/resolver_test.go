@@ -0,0 +1,172 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package resolver
+
+import (
+	"github.com/gdm85/go-dockerclient"
+	"reflect"
+	"testing"
+)
+
+var containers = []docker.APIContainers{
+	{ID: "a1b2c3d4e5f6", Names: []string{"/web-1"}, Labels: map[string]string{"tier": "front"}},
+	{ID: "a1b2ffee1122", Names: []string{"/web-2", "/proxy/backend"}, Labels: map[string]string{"tier": "front"}},
+	{ID: "c0ffee000001", Names: []string{"/cache"}},
+	{ID: "d00d1e550011", Names: []string{"/db"}, Labels: map[string]string{"tier": "data"}},
+	{ID: "e0e0e0e0e0e0", Names: []string{"/ci-build-42"}},
+	{ID: "f0f0f0f0f0f0"},
+}
+
+// names returns the names of containers, for comparison
+func names(containers []*docker.APIContainers) []string {
+	result := []string{}
+	for _, container := range containers {
+		result = append(result, Name(container))
+	}
+	return result
+}
+
+func TestName(t *testing.T) {
+	if name := Name(&containers[1]); name != "web-2" {
+		t.Errorf("got %q, want web-2", name)
+	}
+	if name := Name(&containers[5]); name != "" {
+		t.Errorf("got %q for a container without name", name)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"re:^web-", []string{"web-1", "web-2"}},
+		{"label:tier", []string{"web-1", "web-2", "db"}},
+		{"label:tier=data", []string{"db"}},
+		{"label:tier=", []string{}},
+		{"d00d1e550011", []string{"db"}},
+		{"db", []string{"db"}},
+		{"glob:web-*", []string{"web-1", "web-2"}},
+		{"glob:web-[2-9]", []string{"web-2"}},
+		{"glob:ci-build-?", []string{}},
+		{"glob:*", []string{"web-1", "web-2", "cache", "db", "ci-build-42"}},
+		{"glob:web", []string{}},
+		// patterns without prefix are regular expressions, even with '*', '?' or '['
+		{"build", []string{"ci-build-42"}},
+		{"^web-1$", []string{"web-1"}},
+		{"web-.*", []string{"web-1", "web-2"}},
+		{"web-[0-9]+", []string{"web-1", "web-2"}},
+		{"web-[2-9]", []string{"web-2"}},
+		{"ci-build-?", []string{"ci-build-42"}},
+		{"^[cd]", []string{"cache", "db", "ci-build-42"}},
+		// hex patterns matching names are not taken as ID prefixes
+		{"c", []string{"cache", "ci-build-42"}},
+		{"e", []string{"web-1", "web-2", "cache"}},
+		{"cafe", []string{}},
+		// hex patterns matching no name are ID prefixes
+		{"d00d", []string{"db"}},
+		{"a1b2c", []string{"web-1"}},
+		{"backend", []string{}},
+		{"missing", []string{}},
+	}
+	r := New(containers)
+	for _, test := range tests {
+		matching, err := r.Resolve(test.pattern)
+		if err != nil {
+			t.Errorf("%s: %s", test.pattern, err)
+			continue
+		}
+		if got := names(matching); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	tests := []struct {
+		options Options
+		pattern string
+		want    []string
+	}{
+		{Options{IgnoreCase: true}, "re:^WEB", []string{"web-1", "web-2"}},
+		{Options{IgnoreCase: true}, "glob:CI-*", []string{"ci-build-42"}},
+		{Options{IgnoreCase: true}, "label:tier=DATA", []string{"db"}},
+		{Options{IgnoreCase: true}, "DB", []string{"db"}},
+		{Options{IgnoreCase: true}, "Web-[0-9]", []string{"web-1", "web-2"}},
+		{Options{IgnoreCase: true}, "D00D", []string{"db"}},
+		{Options{}, "re:^WEB", []string{}},
+		{Options{}, "DB", []string{}},
+		{Options{WholeName: true}, "re:web", []string{}},
+		{Options{WholeName: true}, "re:web-.", []string{"web-1", "web-2"}},
+		{Options{WholeName: true}, "build", []string{}},
+		{Options{WholeName: true}, "d00d1e550011", []string{"db"}},
+		// IDs are not matched by prefix
+		{Options{WholeName: true}, "d00d", []string{}},
+		{Options{IgnoreCase: true, WholeName: true}, "CACHE|DB", []string{"cache", "db"}},
+	}
+	for _, test := range tests {
+		matching, err := NewWithOptions(containers, test.options).Resolve(test.pattern)
+		if err != nil {
+			t.Errorf("%s with %+v: %s", test.pattern, test.options, err)
+			continue
+		}
+		if got := names(matching); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s with %+v: got %q, want %q", test.pattern, test.options, got, test.want)
+		}
+	}
+}
+
+func TestPrefixed(t *testing.T) {
+	for pattern, want := range map[string]bool{"re:^web": true, "label:tier": true, "glob:web-*": true, "web-1": false, "db:": false} {
+		if got := Prefixed(pattern); got != want {
+			t.Errorf("%s: got %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	r := New(containers)
+	for _, pattern := range []string{"", "re:(", "label:", "label:=x", "glob:web-[", "web-[", "web-("} {
+		if _, err := r.Resolve(pattern); err == nil {
+			t.Errorf("%q: got no error", pattern)
+		}
+	}
+
+	_, err := r.Resolve("a1b2")
+	ambiguous, ok := err.(*AmbiguousError)
+	if !ok {
+		t.Fatalf("a1b2: got %v, want an *AmbiguousError", err)
+	}
+	want := []string{"web-1 (a1b2c3d4e5f6)", "web-2 (a1b2ffee1122)"}
+	if !reflect.DeepEqual(ambiguous.Candidates, want) {
+		t.Errorf("a1b2: got candidates %q, want %q", ambiguous.Candidates, want)
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	matching, err := New(containers).ResolveAll([]string{"web-2", "label:tier", "missing", "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"web-2", "web-1", "db"}
+	if got := names(matching); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package selector parses and evaluates the --where expressions used to
+// select containers by their inspect data.
+//
+// An expression is a comma-separated list of conditions which must all hold;
+// several expressions select containers matching any of them. A condition is
+// a field, an operator and a value, e.g. name~^ci-, image=redis:*,
+// label.com.example.job=42, status=exited, exitcode!=0 or created<2h. A
+// label condition without operator checks that the label is set, and a
+// leading '!' negates any condition.
+package selector
+
+import (
+	"fmt"
+	"github.com/gdm85/go-dockerclient"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const labelPrefix = "label."
+
+// operators, longest first so that e.g. '!=' is not parsed as '!'
+var operators = []string{"!=", "!~", "<=", ">=", "=", "~", "<", ">"}
+
+// kinds of fields, each allowing a set of operators
+const (
+	kindString = iota
+	kindNumber
+	kindAge
+)
+
+// field accessors; only the one matching kind is set
+type field struct {
+	kind   int
+	text   func(container *docker.Container) string
+	number func(container *docker.Container) int
+	time   func(container *docker.Container) time.Time
+}
+
+var fields = map[string]field{
+	"name": {kind: kindString, text: func(container *docker.Container) string {
+		return strings.TrimPrefix(container.Name, "/")
+	}},
+	"id": {kind: kindString, text: func(container *docker.Container) string {
+		return container.ID
+	}},
+	"image": {kind: kindString, text: func(container *docker.Container) string {
+		if container.Config == nil {
+			return container.Image
+		}
+		return container.Config.Image
+	}},
+	"status": {kind: kindString, text: Status},
+	"hostname": {kind: kindString, text: func(container *docker.Container) string {
+		if container.Config == nil {
+			return ""
+		}
+		return container.Config.Hostname
+	}},
+	"exitcode": {kind: kindNumber, number: func(container *docker.Container) int {
+		return container.State.ExitCode
+	}},
+	"restarts": {kind: kindNumber, number: func(container *docker.Container) int {
+		return container.RestartCount
+	}},
+	"created": {kind: kindAge, time: func(container *docker.Container) time.Time {
+		return container.Created
+	}},
+	"started": {kind: kindAge, time: func(container *docker.Container) time.Time {
+		return container.State.StartedAt
+	}},
+}
+
+// Status returns the status of a container as named by the Docker API:
+// created, running, paused, restarting, removing, exited or dead
+func Status(container *docker.Container) string {
+	state := &container.State
+	if state.Status != "" {
+		return state.Status
+	}
+
+	switch {
+	case state.Paused:
+		return "paused"
+	case state.Restarting:
+		return "restarting"
+	case state.Running:
+		return "running"
+	case state.RemovalInProgress:
+		return "removing"
+	case state.Dead:
+		return "dead"
+	case state.StartedAt.IsZero():
+		return "created"
+	}
+	return "exited"
+}
+
+type condition struct {
+	name   string
+	label  string
+	field  field
+	op     string
+	value  string
+	negate bool
+	// compiled value for string fields, and parsed one for numbers and ages
+	rx       *regexp.Regexp
+	number   int
+	duration time.Duration
+}
+
+// Selector is a parsed set of expressions
+type Selector struct {
+	alternatives [][]*condition
+}
+
+// Parse parses expressions; the resulting selector matches containers
+// matching any of them
+func Parse(exprs []string) (*Selector, error) {
+	s := &Selector{}
+	for _, expr := range exprs {
+		var conditions []*condition
+		for _, term := range strings.Split(expr, ",") {
+			c, err := parseCondition(strings.TrimSpace(term))
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector '%s': %s", expr, err)
+			}
+			conditions = append(conditions, c)
+		}
+		s.alternatives = append(s.alternatives, conditions)
+	}
+	return s, nil
+}
+
+func parseCondition(term string) (*condition, error) {
+	c := &condition{}
+	if strings.HasPrefix(term, "!") {
+		c.negate = true
+		term = term[1:]
+	}
+
+	end := strings.IndexAny(term, "=!~<>")
+	if end == -1 {
+		c.name = term
+	} else {
+		c.name = term[:end]
+		for _, op := range operators {
+			if strings.HasPrefix(term[end:], op) {
+				c.op = op
+				c.value = term[end+len(op):]
+				break
+			}
+		}
+		if c.op == "" {
+			return nil, fmt.Errorf("invalid operator in '%s'", term)
+		}
+	}
+	if c.name == "" {
+		return nil, fmt.Errorf("missing field in '%s'", term)
+	}
+
+	if strings.HasPrefix(c.name, labelPrefix) {
+		c.label = c.name[len(labelPrefix):]
+		if c.label == "" {
+			return nil, fmt.Errorf("missing label name in '%s'", term)
+		}
+		c.field = field{kind: kindString}
+		// no operator checks for the presence of the label
+		if c.op == "" {
+			return c, nil
+		}
+	} else {
+		var ok bool
+		c.field, ok = fields[c.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field '%s'", c.name)
+		}
+		if c.op == "" {
+			return nil, fmt.Errorf("missing operator after '%s'", c.name)
+		}
+	}
+
+	var err error
+	switch c.field.kind {
+	case kindString:
+		switch c.op {
+		case "=", "!=":
+			c.rx, err = globRegexp(c.value)
+		case "~", "!~":
+			c.rx, err = regexp.Compile(c.value)
+		default:
+			return nil, fmt.Errorf("operator '%s' cannot be used with '%s'", c.op, c.name)
+		}
+	case kindNumber:
+		c.number, err = strconv.Atoi(c.value)
+		if c.op == "~" || c.op == "!~" {
+			return nil, fmt.Errorf("operator '%s' cannot be used with '%s'", c.op, c.name)
+		}
+	case kindAge:
+		c.duration, err = ParseDuration(c.value)
+		if c.op != "<" && c.op != "<=" && c.op != ">" && c.op != ">=" {
+			return nil, fmt.Errorf("operator '%s' cannot be used with '%s'", c.op, c.name)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid value in '%s': %s", term, err)
+	}
+
+	return c, nil
+}
+
+// globRegexp compiles a glob where '*' matches any sequence of characters,
+// including slashes, and '?' any single character
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	rx := regexp.QuoteMeta(glob)
+	rx = strings.Replace(rx, `\*`, ".*", -1)
+	rx = strings.Replace(rx, `\?`, ".", -1)
+	return regexp.Compile("^" + rx + "$")
+}
+
+// ParseDuration accepts Go durations plus days, e.g. 2h, 90m or 3d
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Match returns true if the container matches any of the expressions
+func (s *Selector) Match(container *docker.Container) bool {
+	for _, conditions := range s.alternatives {
+		matched := true
+		for _, c := range conditions {
+			if c.match(container) == c.negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *condition) match(container *docker.Container) bool {
+	if c.label != "" {
+		var value string
+		var ok bool
+		if container.Config != nil {
+			value, ok = container.Config.Labels[c.label]
+		}
+		// a missing label never equals nor differs from a value
+		if c.op == "" || !ok {
+			return ok
+		}
+		return c.rx.MatchString(value) == (c.op == "=" || c.op == "~")
+	}
+
+	switch c.field.kind {
+	case kindString:
+		return c.rx.MatchString(c.field.text(container)) == (c.op == "=" || c.op == "~")
+	case kindNumber:
+		return compare(c.op, int64(c.field.number(container)), int64(c.number))
+	case kindAge:
+		t := c.field.time(container)
+		// never started containers have a zero time
+		if t.IsZero() || t.Year() <= 1 {
+			return false
+		}
+		return compare(c.op, int64(time.Since(t)), int64(c.duration))
+	}
+	return false
+}
+
+func compare(op string, a, b int64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
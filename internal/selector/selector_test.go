@@ -0,0 +1,164 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package selector
+
+import (
+	"github.com/gdm85/go-dockerclient"
+	"testing"
+	"time"
+)
+
+var (
+	web = &docker.Container{
+		ID:      "a1b2c3d4e5f6",
+		Name:    "/web-1",
+		Created: time.Now().Add(-3 * 24 * time.Hour),
+		Config:  &docker.Config{Image: "nginx:1.25", Hostname: "web-1", Labels: map[string]string{"tier": "front"}},
+		State:   docker.State{Running: true, StartedAt: time.Now().Add(-time.Hour)},
+	}
+	job = &docker.Container{
+		ID:           "e0e0e0e0e0e0",
+		Name:         "/ci-build-42",
+		Created:      time.Now().Add(-30 * time.Minute),
+		Config:       &docker.Config{Image: "registry.example.com/ci/runner:7", Labels: map[string]string{"com.example.job": "42", "tier": ""}},
+		State:        docker.State{ExitCode: 1, StartedAt: time.Now().Add(-20 * time.Minute)},
+		RestartCount: 2,
+	}
+	created = &docker.Container{
+		ID:      "c0c0c0c0c0c0",
+		Name:    "/batch",
+		Created: time.Now().Add(-time.Minute),
+		Config:  &docker.Config{Image: "busybox"},
+	}
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		state docker.State
+		want  string
+	}{
+		{docker.State{Status: "removing", Running: true}, "removing"},
+		{docker.State{Running: true, Paused: true}, "paused"},
+		{docker.State{Running: true, Restarting: true}, "restarting"},
+		{docker.State{Running: true}, "running"},
+		{docker.State{RemovalInProgress: true}, "removing"},
+		{docker.State{Dead: true}, "dead"},
+		{docker.State{}, "created"},
+		{docker.State{StartedAt: time.Now()}, "exited"},
+	}
+	for _, test := range tests {
+		if got := Status(&docker.Container{State: test.state}); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.state, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		exprs []string
+		want  []bool // for web, job and created
+	}{
+		{[]string{"name=web-1"}, []bool{true, false, false}},
+		{[]string{"name=web-*"}, []bool{true, false, false}},
+		{[]string{"name~^ci-"}, []bool{false, true, false}},
+		{[]string{"name!~^ci-"}, []bool{true, false, true}},
+		{[]string{"id=a1b2*"}, []bool{true, false, false}},
+		{[]string{"image=*/ci/*"}, []bool{false, true, false}},
+		{[]string{"hostname=web-?"}, []bool{true, false, false}},
+		{[]string{"status=exited"}, []bool{false, true, false}},
+		{[]string{"status!=running"}, []bool{false, true, true}},
+		{[]string{"exitcode!=0"}, []bool{false, true, false}},
+		{[]string{"restarts>=2"}, []bool{false, true, false}},
+		{[]string{"created<1h"}, []bool{false, true, true}},
+		{[]string{"created>2d"}, []bool{true, false, false}},
+		// never started containers match no age condition
+		{[]string{"started<1d"}, []bool{true, true, false}},
+		{[]string{"started>=0s"}, []bool{true, true, false}},
+		{[]string{"label.tier"}, []bool{true, true, false}},
+		{[]string{"!label.tier"}, []bool{false, false, true}},
+		{[]string{"label.tier=front"}, []bool{true, false, false}},
+		// a missing label neither equals nor differs from a value
+		{[]string{"label.tier!=front"}, []bool{false, true, false}},
+		{[]string{"label.com.example.job~^4"}, []bool{false, true, false}},
+		{[]string{"status=exited,exitcode=0"}, []bool{false, false, false}},
+		{[]string{"status=exited, label.com.example.job"}, []bool{false, true, false}},
+		{[]string{"status=running", "status=created"}, []bool{true, false, true}},
+	}
+	containers := []*docker.Container{web, job, created}
+	for _, test := range tests {
+		s, err := Parse(test.exprs)
+		if err != nil {
+			t.Errorf("%q: %s", test.exprs, err)
+			continue
+		}
+		for i, container := range containers {
+			if got := s.Match(container); got != test.want[i] {
+				t.Errorf("%q on %s: got %v, want %v", test.exprs, container.Name, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"name",
+		"=web-1",
+		"label.=x",
+		"size>1",
+		"name<web",
+		"name~(",
+		"exitcode~1",
+		"exitcode=one",
+		"created=1h",
+		"created<soon",
+		"name!web",
+	} {
+		if _, err := Parse([]string{expr}); err == nil {
+			t.Errorf("%q: got no error", expr)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"90m", 90 * time.Minute},
+		{"2h", 2 * time.Hour},
+		{"3d", 72 * time.Hour},
+		{"0d", 0},
+	}
+	for _, test := range tests {
+		got, err := ParseDuration(test.s)
+		if err != nil {
+			t.Errorf("%s: %s", test.s, err)
+		} else if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.s, got, test.want)
+		}
+	}
+
+	for _, s := range []string{"", "d", "1.5d", "soon"} {
+		if _, err := ParseDuration(s); err == nil {
+			t.Errorf("%q: got no error", s)
+		}
+	}
+}
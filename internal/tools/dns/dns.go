@@ -0,0 +1,318 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package dns implements docker-dns, a DNS responder for the names of the
+// running containers.
+package dns
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/cliplugin"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/inspect"
+	"github.com/gdm85/docker-cli-tools/pkg/dockertools"
+	"github.com/gdm85/go-dockerclient"
+	"github.com/gdm85/goopt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	listen       *string
+	domain       *string
+	forward      *string
+	ttl          *int
+	refreshValue *string
+	verbose      *bool
+)
+
+// table maps the names of containers to their addresses and back
+type table struct {
+	domain string
+
+	mu    sync.RWMutex
+	names map[string][]net.IP
+	ptr   map[string]string
+}
+
+// refresh rebuilds the table from the running containers
+func (t *table) refresh(client *docker.Client) error {
+	containers, err := client.ListContainers(docker.ListContainersOptions{})
+	if err != nil {
+		return err
+	}
+
+	inspectCache := inspect.NewCache(client)
+	IDs := make([]string, len(containers))
+	for i, container := range containers {
+		IDs[i] = container.ID
+	}
+	inspectCache.Prefetch(IDs, inspect.DefaultParallel)
+
+	names := map[string][]net.IP{}
+	ptr := map[string]string{}
+	for _, ID := range IDs {
+		container, err := inspectCache.Get(ID)
+		if err != nil {
+			// removed in the meanwhile
+			continue
+		}
+
+		var ips []net.IP
+		for _, address := range dockertools.GetAddresses(container) {
+			for _, s := range []string{address.IPAddress, address.GlobalIPv6Address} {
+				if ip := net.ParseIP(s); ip != nil {
+					ips = append(ips, ip)
+				}
+			}
+		}
+
+		name := strings.ToLower(container.Name) + "." + t.domain
+		names[name] = append(names[name], ips...)
+		for _, ip := range ips {
+			if _, ok := ptr[ip.String()]; !ok {
+				ptr[ip.String()] = name
+			}
+		}
+
+		// the hostname file is not readable for remote daemons
+		hostname := strings.ToLower(dockertools.ConfigHostname(container))
+		if hostname != "" && hostname != strings.ToLower(container.Name) {
+			name = hostname + "." + t.domain
+			names[name] = append(names[name], ips...)
+		}
+	}
+
+	t.mu.Lock()
+	t.names, t.ptr = names, ptr
+	t.mu.Unlock()
+
+	if *verbose {
+		fmt.Fprintf(os.Stderr, "docker-dns: %d names for %d containers\n", len(names), len(IDs))
+	}
+	return nil
+}
+
+// follow refreshes the table on container and network events, and
+// periodically in case events are missed or not available
+func (t *table) follow(client *docker.Client, interval time.Duration) {
+	wake := make(chan struct{}, 1)
+	events := make(chan *docker.APIEvents, 16)
+	err := client.AddEventListener(events)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-dns: cannot follow events, refreshing every %s: %s\n", interval, err)
+	} else {
+		go func() {
+			for event := range events {
+				if event.Type != "" && event.Type != "container" && event.Type != "network" {
+					continue
+				}
+				// bursts of events cause a single refresh
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-wake:
+		case <-ticker.C:
+		}
+
+		err := t.refresh(client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-dns: %s\n", err)
+		}
+	}
+}
+
+// answer returns the response code and answers for a question, ok is false
+// when it is about neither the domain nor a container address
+func (t *table) answer(q *question) (rcode int, answers []record, ok bool) {
+	if q.qclass != classIN {
+		return 0, nil, false
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if q.name == t.domain || strings.HasSuffix(q.name, "."+t.domain) {
+		ips, found := t.names[q.name]
+		if !found && q.name != t.domain {
+			return rcodeNXDomain, nil, true
+		}
+
+		for _, ip := range ips {
+			if ip4 := ip.To4(); ip4 != nil {
+				if q.qtype == typeA || q.qtype == typeANY {
+					answers = append(answers, record{typeA, ip4})
+				}
+			} else if q.qtype == typeAAAA || q.qtype == typeANY {
+				answers = append(answers, record{typeAAAA, ip.To16()})
+			}
+		}
+		return rcodeNoError, answers, true
+	}
+
+	if q.qtype != typePTR && q.qtype != typeANY {
+		return 0, nil, false
+	}
+	ip := ptrAddress(q.name)
+	if ip == nil {
+		return 0, nil, false
+	}
+	name, found := t.ptr[ip.String()]
+	if !found {
+		return 0, nil, false
+	}
+	return rcodeNoError, []record{{typePTR, encodeName(name)}}, true
+}
+
+// ptrAddress returns the address of a reverse lookup name, nil for other names
+func ptrAddress(name string) net.IP {
+	if strings.HasSuffix(name, ".in-addr.arpa") {
+		parts := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa"), ".")
+		if len(parts) != net.IPv4len {
+			return nil
+		}
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+		return net.ParseIP(strings.Join(parts, "."))
+	}
+
+	if strings.HasSuffix(name, ".ip6.arpa") {
+		nibbles := strings.Split(strings.TrimSuffix(name, ".ip6.arpa"), ".")
+		if len(nibbles) != 2*net.IPv6len {
+			return nil
+		}
+		var s []byte
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			if len(nibbles[i]) != 1 {
+				return nil
+			}
+			s = append(s, nibbles[i][0])
+			if i%4 == 0 && i != 0 {
+				s = append(s, ':')
+			}
+		}
+		return net.ParseIP(string(s))
+	}
+
+	return nil
+}
+
+func showUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: docker-dns [options]")
+	fmt.Fprintln(os.Stderr, "Use --help for the list of options")
+	fmt.Fprintln(os.Stderr, "docker-dns is part of docker-cli-tools and licensed under GNU GPLv2")
+}
+
+const description = "DNS responder for the names and hostnames of running containers."
+
+// registerFlags adds the options of docker-dns to the command line parser
+func registerFlags() {
+	listen = goopt.String([]string{"-l", "--listen"}, "127.0.0.1:5353", "UDP and TCP address to answer queries on")
+	domain = goopt.String([]string{"--domain"}, "docker", "domain of container names, e.g. web-1.docker")
+	forward = goopt.String([]string{"--forward"}, "", "DNS server to forward other queries to; they are refused if not set")
+	ttl = goopt.Int([]string{"--ttl"}, 5, "time to live of answers, in seconds")
+	refreshValue = goopt.String([]string{"--refresh"}, "1m", "interval of full refreshes, besides those caused by events")
+	verbose = goopt.Flag([]string{"-v", "--verbose"}, []string{}, "log refreshes and queries", "")
+}
+
+// Main runs docker-dns with the command line found in os.Args
+func Main() {
+	cliplugin.Handle(description)
+
+	if len(os.Args) == 2 && os.Args[1] == "-h" {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	goopt.Description = func() string {
+		return description
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "docker-dns"
+	dockerenv.RegisterFlags()
+	registerFlags()
+	goopt.Parse(nil)
+
+	if len(goopt.Args) != 0 {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	interval, err := time.ParseDuration(*refreshValue)
+	if err != nil || interval <= 0 {
+		fmt.Fprintf(os.Stderr, "docker-dns: invalid --refresh '%s'\n", *refreshValue)
+		os.Exit(1)
+	}
+
+	client, err := dockerenv.NewClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-dns: %s\n", err)
+		os.Exit(1)
+	}
+
+	t := &table{domain: strings.ToLower(strings.Trim(*domain, "."))}
+	err = t.refresh(client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-dns: %s\n", err)
+		os.Exit(1)
+	}
+
+	s := &server{table: t, ttl: uint32(*ttl), verbose: *verbose}
+	if *forward != "" {
+		s.forward = withPort(*forward)
+	}
+
+	udp, err := net.ListenPacket("udp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-dns: %s\n", err)
+		os.Exit(1)
+	}
+	tcp, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-dns: %s\n", err)
+		os.Exit(1)
+	}
+
+	go t.follow(client, interval)
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- s.serveUDP(udp)
+	}()
+	go func() {
+		errs <- s.serveTCP(tcp)
+	}()
+
+	fmt.Fprintf(os.Stderr, "docker-dns: %s\n", <-errs)
+	os.Exit(1)
+}
@@ -0,0 +1,172 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package dns
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// resource record types and classes, RFC 1035 and RFC 3596
+const (
+	typeA    = 1
+	typePTR  = 12
+	typeAAAA = 28
+	typeANY  = 255
+	classIN  = 1
+)
+
+// response codes
+const (
+	rcodeNoError  = 0
+	rcodeFormErr  = 1
+	rcodeServFail = 2
+	rcodeNXDomain = 3
+	rcodeRefused  = 5
+)
+
+const (
+	headerLen = 12
+	// largest UDP response without EDNS
+	maxUDPLen = 512
+	// pointer to the name of the question, which always follows the header
+	questionPointer = 0xc000 | headerLen
+)
+
+// question is the single question of a query
+type question struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	// offset of the end of the question section in the query
+	end int
+}
+
+// record is an answer, always about the name of the question
+type record struct {
+	rtype uint16
+	data  []byte
+}
+
+// parseQuery returns the question of a standard query
+func parseQuery(msg []byte) (*question, error) {
+	if len(msg) < headerLen {
+		return nil, fmt.Errorf("short message")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 != 0 {
+		return nil, fmt.Errorf("not a query")
+	}
+	if binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return nil, fmt.Errorf("expected a single question")
+	}
+
+	var labels []string
+	offset := headerLen
+	for {
+		if offset >= len(msg) {
+			return nil, fmt.Errorf("truncated name")
+		}
+		length := int(msg[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+		// queries do not use compression
+		if length > 63 || offset+length > len(msg) {
+			return nil, fmt.Errorf("invalid name")
+		}
+		labels = append(labels, string(msg[offset:offset+length]))
+		offset += length
+	}
+	if offset+4 > len(msg) {
+		return nil, fmt.Errorf("truncated question")
+	}
+
+	return &question{
+		name:   strings.ToLower(strings.Join(labels, ".")),
+		qtype:  binary.BigEndian.Uint16(msg[offset:]),
+		qclass: binary.BigEndian.Uint16(msg[offset+2:]),
+		end:    offset + 4,
+	}, nil
+}
+
+// encodeName returns a domain name in wire format
+func encodeName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// buildResponse answers query, whose question is q; answers are dropped and
+// the response flagged as truncated when they do not fit maxLen, if not 0
+func buildResponse(query []byte, q *question, rcode int, answers []record, ttl uint32, maxLen int) []byte {
+	msg := make([]byte, headerLen, maxUDPLen)
+	copy(msg, query[:4])
+	// response with the same opcode and recursion desired bit
+	flags := 0x8000 | binary.BigEndian.Uint16(query[2:])&0x7900 | uint16(rcode)
+	if rcode != rcodeRefused && rcode != rcodeServFail {
+		// authoritative answer
+		flags |= 0x0400
+	}
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	msg = append(msg, query[headerLen:q.end]...)
+
+	count := 0
+	for _, answer := range answers {
+		var rr [12]byte
+		binary.BigEndian.PutUint16(rr[0:], questionPointer)
+		binary.BigEndian.PutUint16(rr[2:], answer.rtype)
+		binary.BigEndian.PutUint16(rr[4:], classIN)
+		binary.BigEndian.PutUint32(rr[6:], ttl)
+		binary.BigEndian.PutUint16(rr[10:], uint16(len(answer.data)))
+		if maxLen > 0 && len(msg)+len(rr)+len(answer.data) > maxLen {
+			// truncated, the client retries over TCP
+			flags |= 0x0200
+			break
+		}
+		msg = append(msg, rr[:]...)
+		msg = append(msg, answer.data...)
+		count++
+	}
+
+	binary.BigEndian.PutUint16(msg[2:], flags)
+	binary.BigEndian.PutUint16(msg[6:], uint16(count))
+	return msg
+}
+
+// buildError answers a malformed query with only its header
+func buildError(query []byte, rcode int) []byte {
+	if len(query) < 4 {
+		return nil
+	}
+	msg := make([]byte, headerLen)
+	copy(msg, query[:4])
+	flags := 0x8000 | binary.BigEndian.Uint16(query[2:])&0x7900 | uint16(rcode)
+	binary.BigEndian.PutUint16(msg[2:], flags)
+	return msg
+}
@@ -0,0 +1,178 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package dns
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	forwardTimeout = 5 * time.Second
+	// TCP connections are closed after this long without queries
+	idleTimeout = 10 * time.Second
+)
+
+type server struct {
+	table *table
+	// upstream server for other names, empty to refuse them
+	forward string
+	ttl     uint32
+	verbose bool
+}
+
+// handle returns the response to a query, nil if there should be none
+func (s *server) handle(query []byte, tcp bool) []byte {
+	q, err := parseQuery(query)
+	if err != nil {
+		return buildError(query, rcodeFormErr)
+	}
+
+	maxLen := maxUDPLen
+	if tcp {
+		maxLen = 0
+	}
+
+	rcode, answers, ok := s.table.answer(q)
+	if ok {
+		if s.verbose {
+			fmt.Fprintf(os.Stderr, "docker-dns: %s type %d: %d answers, rcode %d\n", q.name, q.qtype, len(answers), rcode)
+		}
+		return buildResponse(query, q, rcode, answers, s.ttl, maxLen)
+	}
+
+	if s.forward == "" {
+		return buildResponse(query, q, rcodeRefused, nil, 0, maxLen)
+	}
+	response, err := s.forwardQuery(query, tcp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-dns: cannot forward query for '%s': %s\n", q.name, err)
+		return buildResponse(query, q, rcodeServFail, nil, 0, maxLen)
+	}
+	return response
+}
+
+// forwardQuery relays a query to the upstream server using the same protocol
+func (s *server) forwardQuery(query []byte, tcp bool) ([]byte, error) {
+	network := "udp"
+	if tcp {
+		network = "tcp"
+	}
+	conn, err := net.DialTimeout(network, s.forward, forwardTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(forwardTimeout))
+
+	if tcp {
+		err = writeTCP(conn, query)
+		if err != nil {
+			return nil, err
+		}
+		return readTCP(conn)
+	}
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (s *server) serveUDP(conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			if response := s.handle(query, false); response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}()
+	}
+}
+
+func (s *server) serveTCP(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers the queries of a TCP connection, each prefixed by its length
+func (s *server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(idleTimeout))
+		query, err := readTCP(conn)
+		if err != nil {
+			return
+		}
+
+		response := s.handle(query, true)
+		if response == nil || writeTCP(conn, response) != nil {
+			return
+		}
+	}
+}
+
+func readTCP(r io.Reader) ([]byte, error) {
+	var length [2]byte
+	_, err := io.ReadFull(r, length[:])
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	_, err = io.ReadFull(r, msg)
+	return msg, err
+}
+
+func writeTCP(w io.Writer, msg []byte) error {
+	b := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(b, uint16(len(msg)))
+	_, err := w.Write(append(b, msg...))
+	return err
+}
+
+// withPort adds the standard DNS port to an address without one
+func withPort(address string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), "53")
+}
@@ -0,0 +1,268 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package grep
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/go-dockerclient"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Result is an entry of docker-grep --exec output
+type Result struct {
+	// daemon the container runs on, set only when querying several
+	Host   string `json:",omitempty"`
+	ID     string
+	Name   string
+	Action string
+	// the action was not performed, with --dry-run
+	DryRun bool
+	// why the action failed, empty on success
+	Error string `json:",omitempty"`
+}
+
+var resultFields = []string{"ID", "Name", "Action", "DryRun", "Error"}
+
+// action is what --exec can do with matched containers
+type action struct {
+	// reported on success
+	done string
+	run  func(client *docker.Client, ID string) error
+}
+
+// actionNames lists the actions of --exec in the order of the help text
+var actionNames = []string{"stop", "kill", "rm", "restart", "pause", "unpause", "logs"}
+
+var actions = map[string]action{
+	"stop": {"stopped", func(client *docker.Client, ID string) error {
+		return client.StopContainer(ID, uint(*stopTime))
+	}},
+	"kill": {"killed", func(client *docker.Client, ID string) error {
+		return client.KillContainer(docker.KillContainerOptions{ID: ID, Signal: killSignal})
+	}},
+	"rm": {"removed", func(client *docker.Client, ID string) error {
+		return client.RemoveContainer(docker.RemoveContainerOptions{ID: ID, Force: *force})
+	}},
+	"restart": {"restarted", func(client *docker.Client, ID string) error {
+		return client.RestartContainer(ID, uint(*stopTime))
+	}},
+	"pause": {"paused", func(client *docker.Client, ID string) error {
+		return client.PauseContainer(ID)
+	}},
+	"unpause": {"unpaused", func(client *docker.Client, ID string) error {
+		return client.UnpauseContainer(ID)
+	}},
+	// logs are fetched by logSearch.searchAll
+	"logs": {"", nil},
+}
+
+// signals are the Linux signal numbers, by name without SIG prefix
+var signals = map[string]docker.Signal{
+	"HUP": 1, "INT": 2, "QUIT": 3, "ILL": 4, "TRAP": 5, "ABRT": 6, "BUS": 7, "FPE": 8,
+	"KILL": 9, "USR1": 10, "SEGV": 11, "USR2": 12, "PIPE": 13, "ALRM": 14, "TERM": 15, "STKFLT": 16,
+	"CHLD": 17, "CONT": 18, "STOP": 19, "TSTP": 20, "TTIN": 21, "TTOU": 22, "URG": 23, "XCPU": 24,
+	"XFSZ": 25, "VTALRM": 26, "PROF": 27, "WINCH": 28, "IO": 29, "PWR": 30, "SYS": 31,
+}
+
+// killSignal is the parsed --signal
+var killSignal docker.Signal = signals["KILL"]
+
+// parseSignal accepts a signal number or name, with or without SIG prefix
+func parseSignal(value string) (docker.Signal, error) {
+	if n, err := strconv.Atoi(value); err == nil && n > 0 && n < 65 {
+		return docker.Signal(n), nil
+	}
+	signal, ok := signals[strings.TrimPrefix(strings.ToUpper(value), "SIG")]
+	if !ok {
+		return 0, fmt.Errorf("invalid signal '%s'", value)
+	}
+	return signal, nil
+}
+
+// apiMessage returns the message of an API error, without the JSON envelope
+func apiMessage(err error) string {
+	if e, ok := err.(*docker.Error); ok {
+		var body struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal([]byte(e.Message), &body) == nil && body.Message != "" {
+			return body.Message
+		}
+	}
+	return err.Error()
+}
+
+// stdinIsTerminal tells whether the user can be asked for confirmation
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks the user whether to perform the action on the matches
+func confirm(name string, matches []interface{}) (bool, error) {
+	if !stdinIsTerminal() {
+		return false, fmt.Errorf("%d containers match, more than --confirm-above %d; specify --yes to %s them", len(matches), *confirmAbove, name)
+	}
+
+	for _, entry := range matches {
+		match := entry.(*Match)
+		if match.Host != "" {
+			fmt.Fprintf(os.Stderr, "  %s\t%s\n", match.Host, match.Name)
+		} else {
+			fmt.Fprintf(os.Stderr, "  %s\n", match.Name)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s %d containers? [y/N] ", name, len(matches))
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		// no answer, end the prompt line
+		fmt.Fprintln(os.Stderr)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// runExec performs the --exec action on the containers matched by queryFunc
+// on all endpoints, once confirmed, and returns the exit status; logs selects
+// the lines shown by the logs action
+func runExec(endpoints []*dockerenv.Endpoint, queryFunc fanout.QueryFunc, logs *logSearch) int {
+	name := *execAction
+	act := actions[name]
+
+	// never act on a partial list of containers
+	matches, status := fanout.Collect("docker-grep", endpoints, queryFunc)
+	if status != 0 {
+		return status
+	}
+
+	if name != "logs" && !*dryRun && !*yes && len(matches) > *confirmAbove {
+		ok, err := confirm(name, matches)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+			return 1
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "docker-grep: aborted\n")
+			return 1
+		}
+	}
+
+	var out *output.Writer
+	var err error
+	// logs change nothing, --dry-run does not apply to them
+	if name == "logs" {
+		out, err = output.NewWriter(logFields, func(entry interface{}) string {
+			return formatLogLine(entry, false)
+		})
+	} else {
+		out, err = output.NewWriter(resultFields, func(entry interface{}) string {
+			result := entry.(*Result)
+			switch {
+			case result.DryRun:
+				return result.Name + "\twould " + result.Action
+			case result.Error != "":
+				return result.Name + "\tfailed to " + result.Action + ": " + result.Error
+			}
+			return result.Name + "\t" + act.done
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+		return 1
+	}
+	logOut := newLogWriter(out, logs)
+
+	return fanout.Run("docker-grep", endpoints, out, func(host string, client *docker.Client) ([]interface{}, error) {
+		var hostMatches []*Match
+		for _, entry := range matches {
+			if match := entry.(*Match); match.Host == host {
+				hostMatches = append(hostMatches, match)
+			}
+		}
+
+		if name == "logs" {
+			return nil, logs.searchAll(client, hostMatches, logOut)
+		}
+		return perform(client, name, act, hostMatches)
+	})
+}
+
+// forEach calls f with the index of each of the n matches, using at most
+// --parallel concurrent calls
+func forEach(n int, f func(i int)) {
+	parallel := *parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				f(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+
+	wg.Wait()
+}
+
+// perform performs an action on the matches of a daemon, returning the
+// result of each of them in the order of the matches
+func perform(client *docker.Client, name string, act action, matches []*Match) ([]interface{}, error) {
+	results := make([]interface{}, len(matches))
+	failed := 0
+	var mu sync.Mutex
+	forEach(len(matches), func(i int) {
+		match := matches[i]
+		result := &Result{Host: match.Host, ID: match.ID, Name: match.Name, Action: name, DryRun: *dryRun}
+		if !*dryRun {
+			if err := act.run(client, match.ID); err != nil {
+				result.Error = apiMessage(err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}
+		results[i] = result
+	})
+
+	if failed != 0 {
+		return results, fanout.Errorf(3, "failed to %s %d of %d containers", name, failed, len(matches))
+	}
+	return results, nil
+}
@@ -0,0 +1,147 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package grep
+
+import (
+	"fmt"
+	"github.com/gdm85/go-dockerclient"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// field is a part of inspect data patterns can be matched against
+type field struct {
+	name   string
+	values func(container *docker.Container) []string
+}
+
+// fields are listed in the order matches are explained
+var fields = []field{
+	{"name", func(container *docker.Container) []string {
+		return []string{container.Name}
+	}},
+	{"image", func(container *docker.Container) []string {
+		return []string{container.Config.Image}
+	}},
+	{"label", func(container *docker.Container) []string {
+		var values []string
+		for key, value := range container.Config.Labels {
+			values = append(values, key+"="+value)
+		}
+		return values
+	}},
+	{"env", func(container *docker.Container) []string {
+		return container.Config.Env
+	}},
+	{"cmd", func(container *docker.Container) []string {
+		return []string{strings.Join(append([]string{container.Path}, container.Args...), " ")}
+	}},
+	{"mount", func(container *docker.Container) []string {
+		var values []string
+		for _, mount := range container.Mounts {
+			values = append(values, mount.Source, mount.Destination)
+		}
+		return values
+	}},
+}
+
+// matcher matches a value equal to the pattern or, failing that, matching
+// it as a regular expression
+type matcher struct {
+	pattern    string
+	rx         *regexp.Regexp
+	ignoreCase bool
+}
+
+// matchOptions are the grep-like options changing how patterns match
+type matchOptions struct {
+	// patterns are fixed strings instead of regular expressions
+	fixed bool
+	// patterns must match whole values
+	whole bool
+	// letter case is ignored
+	ignoreCase bool
+}
+
+func newMatchers(patterns []string, options matchOptions) ([]matcher, error) {
+	matchers := make([]matcher, len(patterns))
+	for i, pattern := range patterns {
+		expr := pattern
+		if options.fixed {
+			expr = regexp.QuoteMeta(pattern)
+		}
+		if options.whole {
+			expr = "^(?:" + expr + ")$"
+		}
+		if options.ignoreCase {
+			expr = "(?i)" + expr
+		}
+
+		rx, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile regex pattern '%s': %s", pattern, err)
+		}
+		matchers[i] = matcher{pattern: pattern, rx: rx, ignoreCase: options.ignoreCase}
+	}
+	return matchers, nil
+}
+
+func (m matcher) match(value string) bool {
+	if m.ignoreCase {
+		return strings.EqualFold(value, m.pattern) || m.rx.MatchString(value)
+	}
+	return value == m.pattern || m.rx.MatchString(value)
+}
+
+// selectFields returns the fields named in names, all of them for "any"
+func selectFields(names []string) []field {
+	var selected []field
+	for _, f := range fields {
+		for _, name := range names {
+			if name == f.name || name == "any" {
+				selected = append(selected, f)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// fieldMatches returns the values of the selected fields of a container that
+// any of the matchers match, as 'field: value'
+func fieldMatches(container *docker.Container, selected []field, matchers []matcher) []string {
+	var matches []string
+	for _, f := range selected {
+		values := f.values(container)
+		if f.name == "label" {
+			sort.Strings(values)
+		}
+		for _, value := range values {
+			for _, m := range matchers {
+				if m.match(value) {
+					matches = append(matches, f.name+": "+value)
+					break
+				}
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,473 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package grep implements docker-grep.
+package grep
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/cliplugin"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/internal/inspect"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/docker-cli-tools/internal/resolver"
+	"github.com/gdm85/docker-cli-tools/internal/selector"
+	"github.com/gdm85/go-dockerclient"
+	"github.com/gdm85/goopt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Match is an entry of docker-grep output
+type Match struct {
+	// daemon the container runs on, set only when querying several
+	Host   string `json:",omitempty"`
+	ID     string
+	Name   string
+	Image  string
+	Status string
+	// fields that matched, with --explain
+	Matched []string `json:",omitempty"`
+	// creation time, for --sort created
+	created int64
+}
+
+// countEntry is the output of docker-grep --count
+type countEntry struct {
+	Host  string
+	Count int
+}
+
+type byName []*Match
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// byCreated sorts from the oldest container, by name when created together
+type byCreated []*Match
+
+func (s byCreated) Len() int      { return len(s) }
+func (s byCreated) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byCreated) Less(i, j int) bool {
+	if s[i].created != s[j].created {
+		return s[i].created < s[j].created
+	}
+	return s[i].Name < s[j].Name
+}
+
+var matchFields = []string{"ID", "Name", "Image", "Status"}
+
+var (
+	parallel *int
+	where    *[]string
+	explain  *bool
+	// grep-like options
+	invert       *bool
+	ignoreCase   *bool
+	count        *bool
+	quiet        *bool
+	fixedStrings *bool
+	wholeName    *bool
+	ids          *bool
+	noTrunc      *bool
+	sortKey      *string
+	// --exec options
+	execAction   *string
+	signalName   *string
+	stopTime     *int
+	force        *bool
+	dryRun       *bool
+	yes          *bool
+	confirmAbove *int
+	// --logs options
+	logsPattern   *string
+	since         *string
+	until         *string
+	stream        *string
+	afterContext  *int
+	beforeContext *int
+	context       *int
+	// fields of inspect data to match patterns against, instead of names
+	fieldFlags = map[string]*bool{}
+)
+
+func showUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: docker-grep [options] pattern1 [pattern2] [pattern3] [...] [patternN]")
+	fmt.Fprintln(os.Stderr, "Use --help for the list of options")
+	fmt.Fprintln(os.Stderr, "docker-grep is part of docker-cli-tools and licensed under GNU GPLv2")
+}
+
+const description = "Quick way to grep container names."
+
+// registerFlags adds the options of docker-grep to the command line parser
+func registerFlags() {
+	parallel = goopt.Int([]string{"--parallel"}, inspect.DefaultParallel, "amount of concurrent inspect requests")
+	where = goopt.Strings([]string{"--where"}, "EXPR", "only match containers matching the selector expression, e.g. image=redis:*,status=running; can be repeated to match any of them")
+	fieldFlags["image"] = goopt.Flag([]string{"--image"}, []string{}, "match patterns against the image", "")
+	fieldFlags["label"] = goopt.Flag([]string{"--label"}, []string{}, "match patterns against labels, as KEY=VALUE", "")
+	fieldFlags["env"] = goopt.Flag([]string{"--env"}, []string{}, "match patterns against environment variables, as NAME=VALUE", "")
+	fieldFlags["cmd"] = goopt.Flag([]string{"--cmd"}, []string{}, "match patterns against the command line", "")
+	fieldFlags["mount"] = goopt.Flag([]string{"--mount"}, []string{}, "match patterns against the source and destination of mounts", "")
+	fieldFlags["any"] = goopt.Flag([]string{"--any"}, []string{}, "match patterns against the name and all the fields above", "")
+	explain = goopt.Flag([]string{"--explain"}, []string{}, "show which fields matched", "")
+	invert = goopt.Flag([]string{"-v", "--invert-match"}, []string{}, "select containers not matching any of the patterns", "")
+	ignoreCase = goopt.Flag([]string{"-i", "--ignore-case"}, []string{}, "ignore case distinctions in patterns and values", "")
+	count = goopt.Flag([]string{"-c", "--count"}, []string{}, "only print the number of matching containers", "")
+	quiet = goopt.Flag([]string{"-q", "--quiet"}, []string{}, "print nothing, exit with status 0 on any match and 1 otherwise", "")
+	fixedStrings = goopt.Flag([]string{"-F", "--fixed-strings"}, []string{}, "interpret patterns as fixed strings instead of regular expressions", "")
+	wholeName = goopt.Flag([]string{"-x", "--whole-name"}, []string{}, "only match whole names or field values", "")
+	ids = goopt.Flag([]string{"--ids"}, []string{}, "print container IDs instead of names", "")
+	noTrunc = goopt.Flag([]string{"--no-trunc"}, []string{}, "do not truncate IDs printed with --ids", "")
+	sortKey = goopt.String([]string{"--sort"}, "name", "sort containers by name or created")
+	execAction = goopt.String([]string{"--exec"}, "", "perform an action on all matched containers: "+strings.Join(actionNames, ", "))
+	signalName = goopt.String([]string{"--signal"}, "", "signal sent by --exec kill, by name or number (default KILL)")
+	stopTime = goopt.Int([]string{"--time"}, 10, "seconds to wait for --exec stop and restart before killing containers")
+	force = goopt.Flag([]string{"--force"}, []string{}, "with --exec rm, remove running containers too", "")
+	dryRun = goopt.Flag([]string{"--dry-run"}, []string{}, "show what --exec would do without doing it", "")
+	yes = goopt.Flag([]string{"--yes"}, []string{}, "do not ask for confirmation before --exec", "")
+	confirmAbove = goopt.Int([]string{"--confirm-above"}, 5, "ask for confirmation when --exec matches more containers than this")
+	logsPattern = goopt.String([]string{"--logs"}, "", "search the logs of matched containers, or all containers without patterns, for lines matching this regular expression")
+	since = goopt.String([]string{"--since"}, "", "only search logs since a time, as duration like 10m, UNIX time or date like 2006-01-02T15:04:05Z")
+	until = goopt.String([]string{"--until"}, "", "only search logs until a time, like --since")
+	stream = goopt.String([]string{"--stream"}, "", "only search logs of a stream, stdout or stderr")
+	afterContext = goopt.Int([]string{"-A", "--after-context"}, 0, "lines of logs to show after each match")
+	beforeContext = goopt.Int([]string{"-B", "--before-context"}, 0, "lines of logs to show before each match")
+	context = goopt.Int([]string{"-C"}, 0, "lines of logs to show before and after each match")
+}
+
+// Main runs docker-grep with the command line found in os.Args
+func Main() {
+	cliplugin.Handle(description)
+
+	// if no arguments specified, show help and exit with failure
+	if len(os.Args) == 2 && os.Args[1] == "-h" {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	goopt.Description = func() string {
+		return description
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "docker-grep"
+	dockerenv.RegisterFlags()
+	registerFlags()
+	output.RegisterFlags(matchFields)
+	goopt.Parse(nil)
+
+	// containers to filter on
+	patterns := goopt.Args
+
+	if len(patterns) == 0 && len(*where) == 0 && *logsPattern == "" {
+		fmt.Fprintf(os.Stderr, "docker-grep: no patterns specified\n")
+		os.Exit(1)
+	}
+
+	var sel *selector.Selector
+	if len(*where) != 0 {
+		var err error
+		sel, err = selector.Parse(*where)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *sortKey != "name" && *sortKey != "created" {
+		fmt.Fprintf(os.Stderr, "docker-grep: invalid sort key '%s', valid keys are name and created\n", *sortKey)
+		os.Exit(1)
+	}
+
+	if *execAction != "" {
+		if _, ok := actions[*execAction]; !ok {
+			fmt.Fprintf(os.Stderr, "docker-grep: invalid --exec action '%s', valid actions are %s\n", *execAction, strings.Join(actionNames, ", "))
+			os.Exit(1)
+		}
+		if *count || *quiet || *logsPattern != "" {
+			fmt.Fprintf(os.Stderr, "docker-grep: --exec cannot be combined with --count, --quiet or --logs\n")
+			os.Exit(1)
+		}
+	}
+	if *logsPattern != "" && (*count || *quiet) {
+		fmt.Fprintf(os.Stderr, "docker-grep: --logs cannot be combined with --count or --quiet\n")
+		os.Exit(1)
+	}
+	logs, err := newLogSearch()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+		os.Exit(1)
+	}
+	if *signalName != "" {
+		if *execAction != "kill" {
+			fmt.Fprintf(os.Stderr, "docker-grep: --signal can only be used with --exec kill\n")
+			os.Exit(1)
+		}
+		killSignal, err = parseSignal(*signalName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var names []string
+	for name, flag := range fieldFlags {
+		if *flag {
+			names = append(names, name)
+		}
+	}
+	selected := selectFields(names)
+
+	options := matchOptions{fixed: *fixedStrings, whole: *wholeName, ignoreCase: *ignoreCase}
+	// container patterns honor -i and -x, while fixed strings need names to
+	// be matched like any other field
+	if len(selected) == 0 && options.fixed {
+		for _, pattern := range patterns {
+			if resolver.Prefixed(pattern) {
+				fmt.Fprintf(os.Stderr, "docker-grep: -F takes patterns as fixed strings, '%s' cannot be used as container pattern\n", pattern)
+				os.Exit(1)
+			}
+		}
+		selected = selectFields([]string{"name"})
+	}
+
+	out, err := output.NewWriter(matchFields, func(entry interface{}) string {
+		match := entry.(*Match)
+		line := match.Name
+		if *ids {
+			line = match.ID
+			if !*noTrunc && len(line) > 12 {
+				line = line[:12]
+			}
+		}
+		if len(match.Matched) != 0 {
+			return line + "\t" + strings.Join(match.Matched, ", ")
+		}
+		return line
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+		os.Exit(1)
+	}
+	if (*count || *quiet) && out.Structured() {
+		fmt.Fprintf(os.Stderr, "docker-grep: --count and --quiet cannot be combined with --format, --json, --jsonl or --csv\n")
+		os.Exit(1)
+	}
+
+	endpoints, err := dockerenv.Endpoints()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+		os.Exit(1)
+	}
+
+	queryFunc := func(host string, client *docker.Client) ([]interface{}, error) {
+		return query(host, client, patterns, selected, options, sel)
+	}
+
+	if *execAction != "" {
+		os.Exit(runExec(endpoints, queryFunc, logs))
+	}
+
+	if *logsPattern != "" {
+		out, err = output.NewWriter(logFields, func(entry interface{}) string {
+			return formatLogLine(entry, true)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-grep: %s\n", err)
+			os.Exit(1)
+		}
+		// lines are written as they are found, not returned
+		logOut := newLogWriter(out, logs)
+		queryMatches := queryFunc
+		queryFunc = func(host string, client *docker.Client) ([]interface{}, error) {
+			entries, err := queryMatches(host, client)
+			if err != nil {
+				return nil, err
+			}
+			matches := make([]*Match, len(entries))
+			for i, entry := range entries {
+				matches[i] = entry.(*Match)
+			}
+			return nil, logs.searchAll(client, matches, logOut)
+		}
+	}
+
+	if *quiet {
+		// like grep, failures are status 2 or above, unless something matched
+		entries, status := fanout.Collect("docker-grep", endpoints, queryFunc)
+		if len(entries) != 0 {
+			os.Exit(0)
+		}
+		if status == 0 {
+			os.Exit(1)
+		}
+		if status < 2 {
+			status = 2
+		}
+		os.Exit(status)
+	}
+
+	if *count {
+		out, _ = output.NewWriter(nil, func(entry interface{}) string {
+			return strconv.Itoa(entry.(*countEntry).Count)
+		})
+		queryMatches := queryFunc
+		queryFunc = func(host string, client *docker.Client) ([]interface{}, error) {
+			entries, err := queryMatches(host, client)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{&countEntry{Host: host, Count: len(entries)}}, nil
+		}
+	}
+
+	// no matches is still a success
+	status := fanout.Run("docker-grep", endpoints, out, queryFunc)
+	if status != 0 {
+		os.Exit(status)
+	}
+}
+
+// query lists the containers of a daemon matching any of the patterns, by
+// name or in the selected fields when any, or all of them when there are no
+// patterns, and the selector when not nil; containers are sorted as
+// specified with --sort
+func query(host string, client *docker.Client, patterns []string, selected []field, options matchOptions, sel *selector.Selector) ([]interface{}, error) {
+	// fetch all containers data
+	allContainers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// names normally come with list data, inspect only when they do not
+	inspectCache := inspect.NewCache(client)
+	err = inspectCache.CompleteNames(allContainers, *parallel)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := map[string]*docker.APIContainers{}
+	// fields that matched, by name
+	matched := map[string][]string{}
+	if len(patterns) == 0 {
+		for i := range allContainers {
+			if name := resolver.Name(&allContainers[i]); name != "" {
+				matching[name] = &allContainers[i]
+			}
+		}
+	} else if len(selected) == 0 {
+		r := resolver.NewWithOptions(allContainers, resolver.Options{IgnoreCase: options.ignoreCase, WholeName: options.whole})
+		for _, pattern := range patterns {
+			containers, err := r.Resolve(pattern)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, container := range containers {
+				matching[resolver.Name(container)] = container
+			}
+
+			// quit matching if everything was already matched
+			if len(matching) == len(allContainers) {
+				break
+			}
+		}
+		for name := range matching {
+			matched[name] = []string{"name: " + name}
+		}
+	} else {
+		matchers, err := newMatchers(patterns, options)
+		if err != nil {
+			return nil, err
+		}
+
+		// fields are matched against inspect data of all containers
+		IDs := make([]string, len(allContainers))
+		for i, container := range allContainers {
+			IDs[i] = container.ID
+		}
+		inspectCache.Prefetch(IDs, *parallel)
+
+		for i := range allContainers {
+			container := &allContainers[i]
+			inspectData, err := inspectCache.Get(container.ID)
+			if err != nil {
+				return nil, fmt.Errorf("about '%s': %s", resolver.Name(container), err)
+			}
+			if matches := fieldMatches(inspectData, selected, matchers); len(matches) != 0 {
+				matching[inspectData.Name] = container
+				matched[inspectData.Name] = matches
+			}
+		}
+	}
+
+	if *invert {
+		inverted := map[string]*docker.APIContainers{}
+		for i := range allContainers {
+			name := resolver.Name(&allContainers[i])
+			if _, ok := matching[name]; !ok && name != "" {
+				inverted[name] = &allContainers[i]
+			}
+		}
+		matching = inverted
+	}
+
+	if sel != nil {
+		// the selector is evaluated against inspect data
+		IDs := make([]string, 0, len(matching))
+		for _, container := range matching {
+			IDs = append(IDs, container.ID)
+		}
+		inspectCache.Prefetch(IDs, *parallel)
+
+		for name, container := range matching {
+			inspectData, err := inspectCache.Get(container.ID)
+			if err != nil {
+				return nil, fmt.Errorf("about '%s': %s", name, err)
+			}
+			if !sel.Match(inspectData) {
+				delete(matching, name)
+			}
+		}
+	}
+
+	matches := make([]*Match, 0, len(matching))
+	for name, container := range matching {
+		match := &Match{Host: host, ID: container.ID, Name: name, Image: container.Image, Status: container.Status, created: container.Created}
+		if *explain {
+			match.Matched = matched[name]
+		}
+		matches = append(matches, match)
+	}
+	if *sortKey == "created" {
+		sort.Sort(byCreated(matches))
+	} else {
+		sort.Sort(byName(matches))
+	}
+
+	entries := make([]interface{}, len(matches))
+	for i, match := range matches {
+		entries[i] = match
+	}
+	return entries, nil
+}
@@ -0,0 +1,358 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/docker-cli-tools/internal/selector"
+	"github.com/gdm85/go-dockerclient"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLine is an entry of docker-grep --logs and --exec logs output
+type LogLine struct {
+	// daemon the container runs on, set only when querying several
+	Host string `json:",omitempty"`
+	ID   string
+	Name string
+	// stdout or stderr
+	Stream string
+	// as reported by the daemon
+	Time string
+	Line string
+	// line around a match, with -A, -B or -C
+	Context bool `json:",omitempty"`
+}
+
+var logFields = []string{"ID", "Name", "Stream", "Time", "Line", "Context"}
+
+// streamNames are the names of the stream types of the multiplexed log stream
+var streamNames = []string{"stdin", "stdout", "stderr"}
+
+// logSearch selects log lines
+type logSearch struct {
+	// lines to match, all of them when nil
+	rx *regexp.Regexp
+	// context lines shown around matches
+	before, after int
+	// ignored unless set
+	since, until time.Time
+	// stdout or stderr, both when empty
+	stream string
+}
+
+// newLogSearch returns the search specified with --logs and the other log
+// options
+func newLogSearch() (*logSearch, error) {
+	search := &logSearch{before: *beforeContext, after: *afterContext, stream: *stream}
+	if *context != 0 {
+		search.before, search.after = *context, *context
+	}
+	if search.before < 0 || search.after < 0 {
+		return nil, fmt.Errorf("context lines cannot be negative")
+	}
+	if search.stream != "" && search.stream != "stdout" && search.stream != "stderr" {
+		return nil, fmt.Errorf("invalid stream '%s', valid streams are stdout and stderr", search.stream)
+	}
+
+	now := time.Now()
+	var err error
+	if *since != "" {
+		search.since, err = parseTime(*since, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --since: %s", err)
+		}
+	}
+	if *until != "" {
+		search.until, err = parseTime(*until, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --until: %s", err)
+		}
+	}
+
+	if *logsPattern != "" {
+		search.rx, err = regexp.Compile(*logsPattern)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile regex pattern '%s': %s", *logsPattern, err)
+		}
+	}
+	return search, nil
+}
+
+// errUntil stops reading logs past --until
+var errUntil = errors.New("past --until")
+
+// parseTime parses --since and --until, as a duration before now, a UNIX
+// time or an RFC 3339 date and time, local unless a zone is specified
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if d, err := selector.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', expected a duration like 10m, a UNIX time or a date like 2006-01-02T15:04:05Z", value)
+}
+
+// demux splits a log stream into lines, calling line for each of them with
+// the name of its stream. Unless tty is true, the stream is multiplexed in
+// frames made of a header (stream type, 3 zero bytes and the big-endian size
+// of the payload) and the payload; a line can be split across frames, and a
+// last line without newline is still reported
+func demux(r io.Reader, tty bool, line func(stream, text string) error) error {
+	if tty {
+		reader := bufio.NewReader(r)
+		for {
+			text, err := reader.ReadString('\n')
+			if text != "" {
+				if lineErr := line("stdout", strings.TrimSuffix(text, "\n")); lineErr != nil {
+					return lineErr
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// incomplete lines of each stream
+	var pending [3][]byte
+	header := make([]byte, 8)
+	for {
+		_, err := io.ReadFull(r, header)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("truncated log stream header")
+		}
+		if err != nil {
+			return err
+		}
+
+		stream := header[0]
+		if int(stream) >= len(streamNames) {
+			return fmt.Errorf("invalid log stream type %d", stream)
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		_, err = io.ReadFull(r, payload)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("truncated log stream frame")
+		}
+		if err != nil {
+			return err
+		}
+
+		pending[stream] = append(pending[stream], payload...)
+		for {
+			i := bytes.IndexByte(pending[stream], '\n')
+			if i < 0 {
+				break
+			}
+			err = line(streamNames[stream], string(pending[stream][:i]))
+			if err != nil {
+				return err
+			}
+			pending[stream] = pending[stream][i+1:]
+		}
+	}
+
+	for stream, text := range pending {
+		if len(text) != 0 {
+			err := line(streamNames[stream], string(text))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readLogs streams the logs of a container through demux
+func readLogs(client *docker.Client, ID string, search *logSearch, line func(stream, text string) error) error {
+	container, err := client.InspectContainer(ID)
+	if err != nil {
+		return err
+	}
+
+	options := docker.LogsOptions{
+		Container:   ID,
+		Stdout:      search.stream != "stderr",
+		Stderr:      search.stream != "stdout",
+		Timestamps:  true,
+		RawTerminal: true,
+	}
+	if !search.since.IsZero() {
+		options.Since = search.since.Unix()
+	}
+
+	r, w := io.Pipe()
+	options.OutputStream = w
+	options.ErrorStream = w
+	go func() {
+		w.CloseWithError(client.Logs(options))
+	}()
+	err = demux(r, container.Config.Tty, line)
+	// unblock the request when stopping early
+	r.Close()
+	return err
+}
+
+// logWriter writes the log lines found by concurrent searches as soon as
+// they are found; like grep, lines that do not follow the previous one are
+// separated by '--' when context is shown
+type logWriter struct {
+	mu  sync.Mutex
+	out *output.Writer
+	// print separators, only in the plain format
+	separate bool
+	// container and number of the last line written
+	last  *Match
+	index int
+}
+
+func newLogWriter(out *output.Writer, search *logSearch) *logWriter {
+	return &logWriter{out: out, separate: !out.Structured() && (search.before > 0 || search.after > 0)}
+}
+
+// write writes line, the index-th line of the logs of match
+func (w *logWriter) write(match *Match, index int, line *LogLine) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.separate && w.last != nil && (w.last != match || w.index+1 != index) {
+		fmt.Println("--")
+	}
+	w.last, w.index = match, index
+	return w.out.Write(line)
+}
+
+// search writes the log lines of a container selected by search, in the
+// order they were logged
+func (search *logSearch) search(client *docker.Client, match *Match, w *logWriter) error {
+	// lines not shown yet, kept as context for the next match, by index
+	var before []*LogLine
+	var beforeIndex []int
+	after := 0
+	index := -1
+	err := readLogs(client, match.ID, search, func(stream, text string) error {
+		i := strings.IndexByte(text, ' ')
+		if i < 0 {
+			return fmt.Errorf("log line without timestamp: %s", text)
+		}
+		t, err := time.Parse(time.RFC3339Nano, text[:i])
+		if err != nil {
+			return fmt.Errorf("invalid log timestamp: %s", err)
+		}
+		// the daemon only honors whole seconds
+		if !search.since.IsZero() && t.Before(search.since) {
+			return nil
+		}
+		if !search.until.IsZero() && t.After(search.until) {
+			return errUntil
+		}
+		index++
+		entry := &LogLine{Host: match.Host, ID: match.ID, Name: match.Name, Stream: stream, Time: text[:i], Line: text[i+1:]}
+
+		if search.rx == nil || search.rx.MatchString(entry.Line) {
+			for j, previous := range before {
+				if err := w.write(match, beforeIndex[j], previous); err != nil {
+					return err
+				}
+			}
+			before, beforeIndex = before[:0], beforeIndex[:0]
+			after = search.after
+			return w.write(match, index, entry)
+		}
+
+		entry.Context = true
+		if after > 0 {
+			after--
+			return w.write(match, index, entry)
+		}
+		if search.before > 0 {
+			if len(before) == search.before {
+				before, beforeIndex = append(before[:0], before[1:]...), append(beforeIndex[:0], beforeIndex[1:]...)
+			}
+			before, beforeIndex = append(before, entry), append(beforeIndex, index)
+		}
+		return nil
+	})
+	if err == errUntil {
+		err = nil
+	}
+	return err
+}
+
+// formatLogLine renders a log line as 'name | time | line', or with dashes
+// for context lines like grep does; time is omitted unless withTime is true
+func formatLogLine(entry interface{}, withTime bool) string {
+	line := entry.(*LogLine)
+	separator := " | "
+	if line.Context {
+		separator = " - "
+	}
+	if withTime {
+		return line.Name + separator + line.Time + separator + line.Line
+	}
+	return line.Name + separator + line.Line
+}
+
+// searchAll searches the logs of all matches of a daemon concurrently,
+// writing lines to w as soon as they are found
+func (search *logSearch) searchAll(client *docker.Client, matches []*Match, w *logWriter) error {
+	errs := make([]error, len(matches))
+	forEach(len(matches), func(i int) {
+		errs[i] = search.search(client, matches[i], w)
+	})
+
+	failed := 0
+	for i, match := range matches {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "docker-grep: %s: cannot fetch logs: %s\n", match.Name, apiMessage(errs[i]))
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		return fanout.Errorf(3, "failed to fetch logs of %d of %d containers", failed, len(matches))
+	}
+	return nil
+}
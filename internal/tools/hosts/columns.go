@@ -0,0 +1,231 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package hosts
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/pkg/dockertools"
+	"github.com/gdm85/go-dockerclient"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultColumns are shown when --columns is not specified
+const defaultColumns = "name,image,state,ip"
+
+// problemsColumns are shown by --problems when --columns is not specified
+const problemsColumns = "name,image,state,problems"
+
+// timeFormat is used by the created, started and finished columns
+const timeFormat = "2006-01-02 15:04:05"
+
+// columns render the cells of the plain output of docker-hosts; empty values
+// are shown as '-'
+var columns = map[string]func(host *Host) string{
+	"id": func(host *Host) string {
+		if len(host.ID) > 12 {
+			return host.ID[:12]
+		}
+		return host.ID
+	},
+	"name": func(host *Host) string {
+		return dockertools.NameOrHostname(host.Name, host.Hostname)
+	},
+	"hostname": func(host *Host) string {
+		return host.Hostname
+	},
+	"image": func(host *Host) string {
+		return host.Image
+	},
+	"state": func(host *Host) string {
+		return host.State
+	},
+	"ip": func(host *Host) string {
+		return host.IPAddress
+	},
+	"ports": func(host *Host) string {
+		return formatPorts(host.container)
+	},
+	"created": func(host *Host) string {
+		return formatTime(host.container.Created)
+	},
+	"started": func(host *Host) string {
+		return formatTime(host.container.State.StartedAt)
+	},
+	"finished": func(host *Host) string {
+		return formatTime(host.container.State.FinishedAt)
+	},
+	"exitcode": func(host *Host) string {
+		if host.container.State.Running || host.container.State.FinishedAt.IsZero() {
+			return ""
+		}
+		return strconv.Itoa(host.container.State.ExitCode)
+	},
+	"restarts": func(host *Host) string {
+		return strconv.Itoa(host.container.RestartCount)
+	},
+	"health": func(host *Host) string {
+		status, _ := dockertools.GetHealth(host.container)
+		return status
+	},
+	"probe": func(host *Host) string {
+		_, output := dockertools.GetHealth(host.container)
+		return output
+	},
+	"problems": func(host *Host) string {
+		return strings.Join(getProblems(host.container), ", ")
+	},
+	"command": func(host *Host) string {
+		return strings.Join(append([]string{host.container.Path}, host.container.Args...), " ")
+	},
+}
+
+// columnNames lists the columns in the help text and in errors
+var columnNames = "id, name, hostname, image, state, ip, ports, created, started, finished, exitcode, restarts, health, probe, problems, command, labels.KEY"
+
+// parseColumns returns the cell renderers of a comma-separated list of columns
+func parseColumns(list string) ([]func(host *Host) string, error) {
+	var result []func(host *Host) string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, "labels.") && len(name) > len("labels.") {
+			key := name[len("labels."):]
+			result = append(result, func(host *Host) string {
+				return host.container.Config.Labels[key]
+			})
+			continue
+		}
+
+		column, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%s', valid columns are: %s", name, columnNames)
+		}
+		result = append(result, column)
+	}
+	return result, nil
+}
+
+// titles returns the header of a comma-separated list of columns, e.g.
+// NAME or LABELS.KEY
+func titles(list string) []string {
+	var result []string
+	for _, name := range strings.Split(list, ",") {
+		result = append(result, strings.ToUpper(strings.TrimSpace(name)))
+	}
+	return result
+}
+
+// cells renders an entry with the selected columns
+func cells(host *Host, selected []func(host *Host) string) []string {
+	result := make([]string, len(selected))
+	for i, column := range selected {
+		result[i] = column(host)
+		if result[i] == "" {
+			result[i] = "-"
+		}
+	}
+	return result
+}
+
+// getProblems returns why a container needs attention, if it does
+func getProblems(container *docker.Container) []string {
+	return dockertools.GetProblems(container, *restartThreshold, restartWindow, time.Now())
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(timeFormat)
+}
+
+// formatPorts lists the exposed ports, e.g. '0.0.0.0:8080->80/tcp,443/tcp'
+func formatPorts(container *docker.Container) string {
+	if container.NetworkSettings == nil {
+		return ""
+	}
+
+	var ports []string
+	for port, bindings := range container.NetworkSettings.Ports {
+		if len(bindings) == 0 {
+			ports = append(ports, string(port))
+			continue
+		}
+		for _, binding := range bindings {
+			ports = append(ports, fmt.Sprintf("%s:%s->%s", binding.HostIP, binding.HostPort, port))
+		}
+	}
+	sort.Strings(ports)
+	return strings.Join(ports, ",")
+}
+
+// sortKeys compare two entries for --sort
+var sortKeys = map[string]func(a, b *Host) bool{
+	"created": func(a, b *Host) bool {
+		return a.container.Created.Before(b.container.Created)
+	},
+	"name": func(a, b *Host) bool {
+		return a.Name < b.Name
+	},
+	"state": func(a, b *Host) bool {
+		return a.State < b.State
+	},
+	"image": func(a, b *Host) bool {
+		return a.Image < b.Image
+	},
+}
+
+// byKey sorts entries with a sort key, ties being sorted by name
+type byKey struct {
+	entries []interface{}
+	less    func(a, b *Host) bool
+}
+
+func (s byKey) Len() int {
+	return len(s.entries)
+}
+func (s byKey) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+}
+func (s byKey) Less(i, j int) bool {
+	a, b := s.entries[i].(*Host), s.entries[j].(*Host)
+	if s.less(a, b) {
+		return true
+	}
+	if s.less(b, a) {
+		return false
+	}
+	return a.Name < b.Name
+}
+
+// sortHosts orders entries by the given key, keeping the list order when it
+// is empty, and reverses the result if requested
+func sortHosts(entries []interface{}, key string, reverse bool) {
+	if key != "" {
+		sort.Stable(byKey{entries: entries, less: sortKeys[key]})
+	}
+	if reverse {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+}
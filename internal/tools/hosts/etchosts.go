@@ -0,0 +1,263 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package hosts
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/pkg/dockertools"
+	"github.com/gdm85/go-dockerclient"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	blockBegin = "# BEGIN docker-cli-tools"
+	blockEnd   = "# END docker-cli-tools"
+)
+
+// hostsLines returns the hosts(5) lines of the running containers, one per
+// IPv4 and global IPv6 address on each of their networks, the hostname being
+// an alias of the name when it differs
+func hostsLines(entries []interface{}) []string {
+	var lines []string
+	for _, entry := range entries {
+		host := entry.(*Host)
+		if !host.container.State.Running || host.container.State.Paused {
+			continue
+		}
+
+		names := host.Name
+		if host.Hostname != "" && host.Hostname != host.Name {
+			names += " " + host.Hostname
+		}
+		seen := map[string]bool{}
+		for _, address := range dockertools.GetAddresses(host.container) {
+			for _, ip := range []string{address.IPAddress, address.GlobalIPv6Address} {
+				if ip != "" && !seen[ip] {
+					seen[ip] = true
+					lines = append(lines, ip+"\t"+names)
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// findLine returns the offset of the first line of content equal to line,
+// or -1 when there is none
+func findLine(content []byte, line string) int {
+	for offset := 0; offset < len(content); {
+		end := bytes.IndexByte(content[offset:], '\n')
+		if end == -1 {
+			end = len(content)
+		} else {
+			end += offset
+		}
+		if string(content[offset:end]) == line {
+			return offset
+		}
+		offset = end + 1
+	}
+	return -1
+}
+
+// replaceBlock returns content with the managed block replaced by lines, or
+// with the block appended when there is none yet
+func replaceBlock(content []byte, lines []string) ([]byte, error) {
+	var block bytes.Buffer
+	block.WriteString(blockBegin + "\n")
+	for _, line := range lines {
+		block.WriteString(line + "\n")
+	}
+	block.WriteString(blockEnd + "\n")
+
+	begin := findLine(content, blockBegin)
+	if begin == -1 {
+		result := append([]byte{}, content...)
+		if len(result) != 0 && result[len(result)-1] != '\n' {
+			result = append(result, '\n')
+		}
+		return append(result, block.Bytes()...), nil
+	}
+	end := findLine(content[begin:], blockEnd)
+	if end == -1 {
+		return nil, fmt.Errorf("'%s' without '%s'", blockBegin, blockEnd)
+	}
+	end += begin + len(blockEnd)
+	// the newline after the end marker is part of the block
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+
+	result := append([]byte{}, content[:begin]...)
+	result = append(result, block.Bytes()...)
+	return append(result, content[end:]...), nil
+}
+
+// writeBlock updates the managed block of fileName, creating the file if
+// needed; the file is replaced atomically and only when the block changed.
+// A symbolic link is followed to update its target, and a file that cannot
+// be replaced, like a bind-mounted /etc/hosts, is rewritten in place
+func writeBlock(fileName string, lines []string) error {
+	if target, err := filepath.EvalSymlinks(fileName); err == nil {
+		fileName = target
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	content, err := ioutil.ReadFile(fileName)
+	if err == nil {
+		fi, err := os.Stat(fileName)
+		if err != nil {
+			return err
+		}
+		mode = fi.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	updated, err := replaceBlock(content, lines)
+	if err != nil {
+		return fmt.Errorf("cannot update '%s': %s", fileName, err)
+	}
+	if bytes.Equal(content, updated) {
+		return nil
+	}
+
+	if replaceFile(fileName, updated, mode) == nil {
+		return nil
+	}
+	return ioutil.WriteFile(fileName, updated, mode)
+}
+
+// replaceFile atomically replaces fileName with content through a temporary
+// file renamed over it
+func replaceFile(fileName string, content []byte, mode os.FileMode) error {
+	// the temporary file must be on the same filesystem for the rename to be atomic
+	f, err := ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName)+".")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fileName)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+// collect is fanout.Collect also telling whether any daemon answered, even
+// with warnings; containers of a daemon that cannot be queried are then left
+// out, like those that cannot be inspected
+func collect(endpoints []*dockerenv.Endpoint, query fanout.QueryFunc) ([]interface{}, int, bool) {
+	var mu sync.Mutex
+	answered := false
+	entries, status := fanout.Collect("docker-hosts", endpoints, func(host string, client *docker.Client) ([]interface{}, error) {
+		entries, err := query(host, client)
+		if e, ok := err.(*fanout.Error); err == nil || ok && e.Status == 3 {
+			mu.Lock()
+			answered = true
+			mu.Unlock()
+		}
+		return entries, err
+	})
+	return entries, status, answered
+}
+
+// runEtcHosts prints the hosts(5) lines of the containers selected by query, or
+// writes them to the managed block of fileName when not empty; with follow
+// the block is rewritten on each event of followedEvents
+func runEtcHosts(endpoints []*dockerenv.Endpoint, query fanout.QueryFunc, fileName string, follow bool) int {
+	// subscribe before listing, so that no change is missed
+	var events <-chan containerEvent
+	if follow {
+		clients, err := newClients(endpoints)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+			return 1
+		}
+		events, err = listen(endpoints, clients, followedEvents)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-hosts: cannot follow events: %s\n", err)
+			return 1
+		}
+	}
+
+	entries, status, answered := collect(endpoints, query)
+	if fileName == "" {
+		for _, line := range hostsLines(entries) {
+			fmt.Println(line)
+		}
+		return status
+	}
+
+	update := func(entries []interface{}, status int, answered bool) int {
+		if !answered {
+			return status
+		}
+		err := writeBlock(fileName, hostsLines(entries))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+			return 1
+		}
+		return status
+	}
+
+	status = update(entries, status, answered)
+	if !follow {
+		return status
+	}
+
+	// failures are reported and retried with the next event
+	for ev := range events {
+		pending(ev, events)
+		update(collect(endpoints, query))
+	}
+	return 0
+}
+
+// followedEvents are the container and network events changing the managed
+// block
+var followedEvents = map[string]bool{
+	"start":      true,
+	"stop":       true,
+	"die":        true,
+	"pause":      true,
+	"unpause":    true,
+	"rename":     true,
+	"connect":    true,
+	"disconnect": true,
+}
@@ -0,0 +1,335 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package hosts implements docker-hosts.
+package hosts
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/cliplugin"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/internal/inspect"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/docker-cli-tools/internal/resolver"
+	"github.com/gdm85/docker-cli-tools/internal/selector"
+	"github.com/gdm85/docker-cli-tools/pkg/dockertools"
+	"github.com/gdm85/go-dockerclient"
+	"github.com/gdm85/goopt"
+	"os"
+	"time"
+)
+
+// Host is an entry of docker-hosts output
+type Host struct {
+	// daemon the container runs on, set only when querying several
+	Host      string `json:",omitempty"`
+	ID        string
+	Name      string
+	Hostname  string
+	Image     string
+	State     string
+	IPAddress string
+	// inspect data, for the columns that are not output fields
+	container *docker.Container
+}
+
+var hostFields = []string{"ID", "Name", "Hostname", "Image", "State", "IPAddress"}
+
+var (
+	parallel     *int
+	where        *[]string
+	etcHosts     *bool
+	writeFile    *string
+	follow       *bool
+	watch        *bool
+	tree         *bool
+	dot          *bool
+	columnList   *string
+	sortKey      *string
+	reverse      *bool
+	hostnameFrom *string
+	problems     *bool
+	// restart loop detection of --problems and of the problems column
+	restartThreshold   *int
+	restartWindow      time.Duration
+	restartWindowValue *string
+	// cell renderers of --columns
+	selectedColumns []func(host *Host) string
+)
+
+func showUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: docker-hosts [options] [container1] [container2] [...] [containerN]")
+	fmt.Fprintln(os.Stderr, "Use --help for the list of options")
+	fmt.Fprintln(os.Stderr, "docker-hosts is part of docker-cli-tools and licensed under GNU GPLv2")
+}
+
+// getHostname returns the hostname of a container from the source selected
+// with --hostname-from
+func getHostname(client *docker.Client, inspectData *docker.Container) (string, error) {
+	switch *hostnameFrom {
+	case "file":
+		return dockertools.GetHostname(inspectData)
+	case "archive":
+		// the hostname file is only created when the container starts
+		if inspectData.State.StartedAt.IsZero() && !inspectData.State.Running {
+			return dockertools.ConfigHostname(inspectData), nil
+		}
+		return dockertools.FetchHostname(client, inspectData.ID)
+	}
+	return dockertools.ConfigHostname(inspectData), nil
+}
+
+// getHost returns the entry of a container; it is returned, without hostname,
+// along with the error when the hostname cannot be read
+func getHost(client *docker.Client, inspectData *docker.Container) (*Host, error) {
+	hostname, err := getHostname(client, inspectData)
+
+	var image string
+	if inspectData.Config != nil {
+		image = inspectData.Config.Image
+	}
+	return &Host{
+		ID:        inspectData.ID,
+		Name:      inspectData.Name,
+		Hostname:  hostname,
+		Image:     image,
+		State:     dockertools.GetState(inspectData),
+		IPAddress: inspectData.NetworkSettings.IPAddress,
+		container: inspectData,
+	}, err
+}
+
+func getIDOrName(container *docker.APIContainers) string {
+	if name := resolver.Name(container); name != "" {
+		return name
+	}
+
+	return container.ID
+}
+
+const description = "An alternative to 'docker ps' with a more terse output."
+
+// registerFlags adds the options of docker-hosts to the command line parser
+func registerFlags() {
+	parallel = goopt.Int([]string{"--parallel"}, inspect.DefaultParallel, "amount of concurrent inspect requests")
+	where = goopt.Strings([]string{"--where"}, "EXPR", "only show containers matching the selector expression, e.g. status=exited,exitcode!=0; can be repeated to match any of them")
+	etcHosts = goopt.Flag([]string{"--etc-hosts"}, []string{}, "print hosts(5) lines of the running containers", "")
+	writeFile = goopt.String([]string{"--write"}, "", "write the hosts(5) lines to a block delimited by '"+blockBegin+"' and '"+blockEnd+"' of FILE, preserving the rest of it")
+	follow = goopt.Flag([]string{"--follow"}, []string{}, "keep updating the block of --write on container events", "")
+	hostnameFrom = goopt.String([]string{"--hostname-from"}, "config", "source of hostnames: config (container configuration), file (hostname file, readable only on the daemon host) or archive (/etc/hostname downloaded from the container)")
+	problems = goopt.Flag([]string{"--problems"}, []string{}, "only show containers that are unhealthy, OOM-killed, exited with a non-zero code, dead or in a restart loop", "")
+	restartThreshold = goopt.Int([]string{"--restart-threshold"}, 3, "amount of restarts of a container in a restart loop")
+	restartWindowValue = goopt.String([]string{"--restart-window"}, "10m", "a container restarted at least --restart-threshold times is in a restart loop when last started within this duration")
+	columnList = goopt.String([]string{"--columns"}, defaultColumns, "comma-separated columns of the default output, among "+columnNames)
+	sortKey = goopt.String([]string{"--sort"}, "", "sort containers by created, name, state or image")
+	reverse = goopt.Flag([]string{"--reverse"}, []string{}, "reverse the order of containers", "")
+	watch = goopt.Flag([]string{"--watch"}, []string{}, "keep showing the containers, updated on container events", "")
+	tree = goopt.Flag([]string{"--tree"}, []string{}, "show containers grouped by compose project and service, with their links, shared networks and volumes", "")
+	dot = goopt.Flag([]string{"--dot"}, []string{}, "output the groups and dependencies of --tree as a Graphviz graph", "")
+}
+
+// Main runs docker-hosts with the command line found in os.Args
+func Main() {
+	cliplugin.Handle(description)
+
+	// if no arguments specified, show help and exit with failure
+	if len(os.Args) == 2 && os.Args[1] == "-h" {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	goopt.Description = func() string {
+		return description
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "docker-hosts"
+	dockerenv.RegisterFlags()
+	registerFlags()
+	output.RegisterFlags(hostFields)
+	goopt.Parse(nil)
+
+	// the reasons are shown unless other columns are selected
+	if *problems && *columnList == defaultColumns {
+		*columnList = problemsColumns
+	}
+	var err error
+	selectedColumns, err = parseColumns(*columnList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+		os.Exit(1)
+	}
+	out, err := output.NewTableWriter(hostFields, titles(*columnList), func(entry interface{}) []string {
+		return cells(entry.(*Host), selectedColumns)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+		os.Exit(1)
+	}
+	if *hostnameFrom != "config" && *hostnameFrom != "file" && *hostnameFrom != "archive" {
+		fmt.Fprintf(os.Stderr, "docker-hosts: invalid hostname source '%s', valid sources are config, file and archive\n", *hostnameFrom)
+		os.Exit(1)
+	}
+	restartWindow, err = time.ParseDuration(*restartWindowValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-hosts: invalid --restart-window: %s\n", err)
+		os.Exit(1)
+	}
+	if _, ok := sortKeys[*sortKey]; *sortKey != "" && !ok {
+		fmt.Fprintf(os.Stderr, "docker-hosts: invalid sort key '%s', valid keys are created, name, state and image\n", *sortKey)
+		os.Exit(1)
+	}
+
+	if *follow && *writeFile == "" {
+		fmt.Fprintf(os.Stderr, "docker-hosts: --follow requires --write\n")
+		os.Exit(1)
+	}
+	if (*etcHosts || *writeFile != "") && out.Structured() {
+		fmt.Fprintf(os.Stderr, "docker-hosts: --etc-hosts and --write cannot be combined with --format, --json, --jsonl or --csv\n")
+		os.Exit(1)
+	}
+	if *watch && (*etcHosts || *writeFile != "" || out.Structured()) {
+		fmt.Fprintf(os.Stderr, "docker-hosts: --watch can only be used with the default output\n")
+		os.Exit(1)
+	}
+	if (*tree || *dot) && (*tree && *dot || *watch || *etcHosts || *writeFile != "" || out.Structured()) {
+		fmt.Fprintf(os.Stderr, "docker-hosts: --tree and --dot can only be used alone with the default output\n")
+		os.Exit(1)
+	}
+
+	var sel *selector.Selector
+	if len(*where) != 0 {
+		sel, err = selector.Parse(*where)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	endpoints, err := dockerenv.Endpoints()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+		os.Exit(1)
+	}
+
+	queryFunc := func(host string, client *docker.Client) ([]interface{}, error) {
+		return query(host, client, goopt.Args, sel)
+	}
+
+	var status int
+	if *watch {
+		status = runWatch(endpoints, goopt.Args, sel)
+	} else if *tree || *dot {
+		var entries []interface{}
+		entries, status = fanout.Collect("docker-hosts", endpoints, queryFunc)
+		sortHosts(entries, *sortKey, *reverse)
+		if *dot {
+			printDot(entries)
+		} else {
+			printTree(entries)
+		}
+	} else if *etcHosts || *writeFile != "" {
+		status = runEtcHosts(endpoints, queryFunc, *writeFile, *follow)
+	} else {
+		// containers of all daemons are sorted together
+		status = fanout.RunSorted("docker-hosts", endpoints, out, queryFunc, func(entries []interface{}) {
+			sortHosts(entries, *sortKey, *reverse)
+		})
+	}
+	if status != 0 {
+		os.Exit(status)
+	}
+}
+
+// query lists the containers of a daemon matching the given patterns, or all
+// of them when there are none, and the selector when not nil
+func query(host string, client *docker.Client, containerIds []string, sel *selector.Selector) ([]interface{}, error) {
+	// fetch all containers data
+	allContainers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	inspectCache := inspect.NewCache(client)
+	err = inspectCache.CompleteNames(allContainers, *parallel)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []*docker.APIContainers
+	if len(containerIds) == 0 {
+		for i := range allContainers {
+			selected = append(selected, &allContainers[i])
+		}
+	} else {
+		// multiple matches are allowed for regex/glob/label patterns, no match is not an error
+		selected, err = resolver.New(allContainers).ResolveAll(containerIds)
+		if err != nil {
+			return nil, &fanout.Error{Status: 2, Err: err}
+		}
+	}
+
+	// inspect data is needed for all displayed containers, pull it concurrently
+	IDs := make([]string, len(selected))
+	for i, container := range selected {
+		IDs[i] = container.ID
+	}
+	inspectCache.Prefetch(IDs, *parallel)
+
+	// a container that cannot be inspected is skipped, one whose hostname
+	// cannot be read is listed without it; both only cause a warning
+	var entries []interface{}
+	failed := 0
+	for _, container := range selected {
+		inspectData, err := inspectCache.Get(container.ID)
+		if err != nil {
+			warn(host, "about '%s': %s", getIDOrName(container), err)
+			failed++
+			continue
+		}
+		if sel != nil && !sel.Match(inspectData) {
+			continue
+		}
+		if *problems && len(getProblems(inspectData)) == 0 {
+			continue
+		}
+
+		entry, err := getHost(client, inspectData)
+		if err != nil {
+			warn(host, "about '%s': cannot read hostname: %s", getIDOrName(container), err)
+			failed++
+		}
+		entry.Host = host
+		entries = append(entries, entry)
+	}
+
+	if failed != 0 {
+		return entries, fanout.Errorf(3, "%d of %d containers could not be listed completely", failed, len(selected))
+	}
+	return entries, nil
+}
+
+// warn reports a failure about a single container on stderr
+func warn(host, format string, a ...interface{}) {
+	if host != "" {
+		format = host + ": " + format
+	}
+	fmt.Fprintf(os.Stderr, "docker-hosts: warning: "+format+"\n", a...)
+}
@@ -0,0 +1,323 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package hosts
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	projectLabel = "com.docker.compose.project"
+	serviceLabel = "com.docker.compose.service"
+	// groups of containers without compose labels
+	noProject = "(no project)"
+	noService = "(no service)"
+)
+
+// relation is a dependency of a container on another one
+type relation struct {
+	// link, network or volumes
+	kind string
+	// name or ID of the other container, as given when creating the container
+	target string
+	// alias of a link, mode of volumes
+	detail string
+}
+
+// relations returns the links, network namespace sharing and volumes-from
+// dependencies of a container
+func relations(host *Host) []relation {
+	hostConfig := host.container.HostConfig
+	if hostConfig == nil {
+		return nil
+	}
+
+	var result []relation
+	// e.g. '/db:/web-1/database'
+	for _, link := range hostConfig.Links {
+		parts := strings.SplitN(link, ":", 2)
+		r := relation{kind: "link", target: strings.TrimPrefix(parts[0], "/")}
+		if len(parts) == 2 {
+			r.detail = parts[1][strings.LastIndex(parts[1], "/")+1:]
+		}
+		result = append(result, r)
+	}
+	if strings.HasPrefix(hostConfig.NetworkMode, "container:") {
+		result = append(result, relation{kind: "network", target: strings.TrimPrefix(hostConfig.NetworkMode, "container:")})
+	}
+	// e.g. 'data:ro'
+	for _, volumesFrom := range hostConfig.VolumesFrom {
+		parts := strings.SplitN(volumesFrom, ":", 2)
+		r := relation{kind: "volumes", target: parts[0]}
+		if len(parts) == 2 {
+			r.detail = parts[1]
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// describe formats a relation for --tree, with the name of the target
+func (r relation) describe(target string) string {
+	switch r.kind {
+	case "link":
+		if r.detail != "" && r.detail != target {
+			return "link to " + target + " as " + r.detail
+		}
+		return "link to " + target
+	case "network":
+		return "shares the network of " + target
+	}
+	if r.detail != "" {
+		return "volumes from " + target + " (" + r.detail + ")"
+	}
+	return "volumes from " + target
+}
+
+// findTarget returns the entry a relation points to, among those of the same
+// daemon, or nil when it is not listed
+func findTarget(entries []interface{}, from *Host, target string) *Host {
+	for _, entry := range entries {
+		host := entry.(*Host)
+		if host.Host != from.Host {
+			continue
+		}
+		if host.Name == target || host.ID == target || (len(target) >= 12 && strings.HasPrefix(host.ID, target)) {
+			return host
+		}
+	}
+	return nil
+}
+
+// targetName returns the name of the container a relation points to
+func targetName(entries []interface{}, from *Host, target string) string {
+	if host := findTarget(entries, from, target); host != nil {
+		return host.Name
+	}
+	return target
+}
+
+// group is a daemon, compose project or compose service of --tree and --dot
+type group struct {
+	name     string
+	groups   []*group
+	children []*Host
+}
+
+// subgroup returns the named subgroup, adding it if needed
+func (g *group) subgroup(name string) *group {
+	for _, sub := range g.groups {
+		if sub.name == name {
+			return sub
+		}
+	}
+	sub := &group{name: name}
+	g.groups = append(g.groups, sub)
+	return sub
+}
+
+// byGroupName sorts groups by name, those without compose labels last
+type byGroupName []*group
+
+func (s byGroupName) Len() int {
+	return len(s)
+}
+func (s byGroupName) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+func (s byGroupName) Less(i, j int) bool {
+	a, b := s[i].name, s[j].name
+	if (a == noProject || a == noService) != (b == noProject || b == noService) {
+		return b == noProject || b == noService
+	}
+	return a < b
+}
+
+// sortGroups sorts subgroups recursively, keeping the order of containers
+func (g *group) sortGroups() {
+	sort.Sort(byGroupName(g.groups))
+	for _, sub := range g.groups {
+		sub.sortGroups()
+	}
+}
+
+// groupEntries groups entries by daemon when there are several, then by
+// compose project and service; containers without project are not grouped
+// by service
+func groupEntries(entries []interface{}) *group {
+	root := &group{}
+	for _, entry := range entries {
+		host := entry.(*Host)
+		g := root
+		if host.Host != "" {
+			g = g.subgroup(host.Host)
+		}
+
+		labels := host.container.Config.Labels
+		project, service := labels[projectLabel], labels[serviceLabel]
+		if project == "" {
+			g = g.subgroup(noProject)
+		} else {
+			g = g.subgroup(project)
+			if service == "" {
+				service = noService
+			}
+			g = g.subgroup(service)
+		}
+		g.children = append(g.children, host)
+	}
+	root.sortGroups()
+	return root
+}
+
+// treeLine is a line of --tree; only container lines have cells
+type treeLine struct {
+	prefix string
+	text   string
+	cells  []string
+}
+
+// renderTree adds the lines of the content of a group, each prefixed by the
+// branches of its ancestors
+func renderTree(lines []treeLine, entries []interface{}, g *group, indent string) []treeLine {
+	count := len(g.groups) + len(g.children)
+	branch := func(i int) (string, string) {
+		if i == count-1 {
+			return indent + "`-- ", indent + "    "
+		}
+		return indent + "|-- ", indent + "|   "
+	}
+
+	i := 0
+	for _, sub := range g.groups {
+		prefix, subIndent := branch(i)
+		lines = append(lines, treeLine{prefix: prefix, text: sub.name})
+		lines = renderTree(lines, entries, sub, subIndent)
+		i++
+	}
+	for _, host := range g.children {
+		prefix, subIndent := branch(i)
+		lines = append(lines, treeLine{prefix: prefix, cells: cells(host, selectedColumns)})
+
+		rels := relations(host)
+		for j, r := range rels {
+			relPrefix := subIndent + "|-- "
+			if j == len(rels)-1 {
+				relPrefix = subIndent + "`-- "
+			}
+			lines = append(lines, treeLine{prefix: relPrefix, text: r.describe(targetName(entries, host, r.target))})
+		}
+		i++
+	}
+	return lines
+}
+
+// printTree shows entries as a tree of daemons, compose projects, compose
+// services and containers, with the dependencies of each container
+func printTree(entries []interface{}) {
+	root := groupEntries(entries)
+
+	// top level groups are not indented
+	var lines []treeLine
+	for _, g := range root.groups {
+		lines = append(lines, treeLine{text: g.name})
+		lines = renderTree(lines, entries, g, "")
+	}
+
+	// container columns are aligned with each other
+	var rows [][]string
+	for _, line := range lines {
+		if line.cells != nil {
+			rows = append(rows, append([]string{line.prefix + line.cells[0]}, line.cells[1:]...))
+		}
+	}
+	aligned := output.Align(rows)
+
+	for _, line := range lines {
+		if line.cells != nil {
+			fmt.Println(aligned[0])
+			aligned = aligned[1:]
+			continue
+		}
+		fmt.Println(line.prefix + line.text)
+	}
+}
+
+// nodeID identifies a container, by ID or by the name or ID other containers
+// refer to it with, in --dot output
+func nodeID(daemon, ID string) string {
+	if daemon == "" {
+		return strconv.Quote(ID)
+	}
+	return strconv.Quote(daemon + "/" + ID)
+}
+
+// printDot shows entries and their dependencies as a Graphviz graph, with a
+// cluster for each daemon, compose project and compose service
+func printDot(entries []interface{}) {
+	fmt.Println("digraph \"docker-hosts\" {")
+	fmt.Println("\trankdir=LR;")
+	fmt.Println("\tnode [shape=box];")
+
+	clusters := 0
+	var printGroup func(g *group, indent string)
+	printGroup = func(g *group, indent string) {
+		for _, sub := range g.groups {
+			clusters++
+			fmt.Printf("%ssubgraph cluster_%d {\n", indent, clusters)
+			fmt.Printf("%s\tlabel=%s;\n", indent, strconv.Quote(sub.name))
+			printGroup(sub, indent+"\t")
+			fmt.Printf("%s}\n", indent)
+		}
+		for _, host := range g.children {
+			fmt.Printf("%s%s [label=%s];\n", indent, nodeID(host.Host, host.ID), strconv.Quote(host.Name+"\n"+host.State))
+		}
+	}
+	printGroup(groupEntries(entries), "\t")
+
+	// containers that are not listed are shown as dashed nodes
+	missing := map[string]bool{}
+	for _, entry := range entries {
+		host := entry.(*Host)
+		for _, r := range relations(host) {
+			var to string
+			if target := findTarget(entries, host, r.target); target != nil {
+				to = nodeID(target.Host, target.ID)
+			} else {
+				to = nodeID(host.Host, r.target)
+				if !missing[to] {
+					missing[to] = true
+					fmt.Printf("\t%s [label=%s, style=dashed];\n", to, strconv.Quote(r.target))
+				}
+			}
+
+			label := r.kind
+			if r.detail != "" {
+				label += " " + r.detail
+			}
+			fmt.Printf("\t%s -> %s [label=%s];\n", nodeID(host.Host, host.ID), to, strconv.Quote(label))
+		}
+	}
+	fmt.Println("}")
+}
@@ -0,0 +1,403 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package hosts
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/docker-cli-tools/internal/resolver"
+	"github.com/gdm85/docker-cli-tools/internal/selector"
+	"github.com/gdm85/go-dockerclient"
+	"os"
+	"strings"
+	"time"
+)
+
+// containerEvent is an event about a container of endpoints[endpoint]
+type containerEvent struct {
+	endpoint int
+	ID       string
+	action   string
+	time     time.Time
+}
+
+// watchedEvents are the container events that can change a row of --watch
+var watchedEvents = map[string]bool{
+	"create":        true,
+	"start":         true,
+	"restart":       true,
+	"stop":          true,
+	"die":           true,
+	"kill":          true,
+	"oom":           true,
+	"pause":         true,
+	"unpause":       true,
+	"rename":        true,
+	"destroy":       true,
+	"health_status": true,
+}
+
+// listen streams the container events of all clients with one of the
+// given actions; a daemon whose events cannot be listened to is reported and
+// left out, unless none can
+func listen(endpoints []*dockerenv.Endpoint, clients []*docker.Client, actions map[string]bool) (<-chan containerEvent, error) {
+	result := make(chan containerEvent, 64)
+	var lastErr error
+	listening := 0
+	for i, client := range clients {
+		events := make(chan *docker.APIEvents, 16)
+		err := client.AddEventListener(events)
+		if err != nil {
+			if len(clients) == 1 {
+				return nil, err
+			}
+			warn(endpoints[i].Name(), "cannot listen to events: %s", err)
+			lastErr = err
+			continue
+		}
+		listening++
+
+		go func(endpoint int) {
+			for event := range events {
+				ID := event.Actor.ID
+				switch event.Type {
+				case "", "container":
+				case "network":
+					// the actor of network events is the network
+					ID = event.Actor.Attributes["container"]
+				default:
+					continue
+				}
+				action := event.Action
+				if action == "" {
+					action = event.Status
+				}
+				// some actions carry details, e.g. "exec_start: sh"
+				action = strings.SplitN(action, ":", 2)[0]
+				if !actions[action] {
+					continue
+				}
+
+				if ID == "" {
+					ID = event.ID
+				}
+				result <- containerEvent{endpoint: endpoint, ID: ID, action: action, time: time.Unix(event.Time, 0)}
+			}
+		}(i)
+	}
+
+	if listening == 0 {
+		return nil, lastErr
+	}
+	return result, nil
+}
+
+// newClients returns a client for each endpoint
+func newClients(endpoints []*dockerenv.Endpoint) ([]*docker.Client, error) {
+	clients := make([]*docker.Client, len(endpoints))
+	for i, ep := range endpoints {
+		var err error
+		clients[i], err = ep.NewClient()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return clients, nil
+}
+
+// pending returns ev followed by the events already queued behind it, so that
+// a burst of events is shown at once
+func pending(ev containerEvent, events <-chan containerEvent) []containerEvent {
+	batch := []containerEvent{ev}
+	for {
+		select {
+		case ev := <-events:
+			batch = append(batch, ev)
+		default:
+			return batch
+		}
+	}
+}
+
+// row is a container shown by --watch
+type row struct {
+	endpoint int
+	host     *Host
+	// state before the last change, empty for new rows
+	previous string
+	// changed by the last events
+	changed bool
+}
+
+// view is the live list of containers of --watch
+type view struct {
+	endpoints []*dockerenv.Endpoint
+	clients   []*docker.Client
+	patterns  []string
+	sel       *selector.Selector
+	rows      []*row
+	tty       bool
+}
+
+// stdoutIsTerminal tells whether output can be redrawn in place
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runWatch shows the containers selected by the patterns and the selector,
+// then keeps the list up to date on container events: in place when stdout is
+// a terminal, otherwise by printing a line for each change
+func runWatch(endpoints []*dockerenv.Endpoint, patterns []string, sel *selector.Selector) int {
+	clients, err := newClients(endpoints)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+		return 1
+	}
+
+	// subscribe before listing, so that no change is missed
+	events, err := listen(endpoints, clients, watchedEvents)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-hosts: cannot watch events: %s\n", err)
+		return 1
+	}
+
+	// the daemons that cannot be queried are reported and left out
+	entries, status, answered := collect(endpoints, func(host string, client *docker.Client) ([]interface{}, error) {
+		return query(host, client, patterns, sel)
+	})
+	if !answered {
+		return status
+	}
+	sortHosts(entries, *sortKey, *reverse)
+
+	v := &view{endpoints: endpoints, clients: clients, patterns: patterns, sel: sel, tty: stdoutIsTerminal()}
+	for _, entry := range entries {
+		host := entry.(*Host)
+		v.rows = append(v.rows, &row{endpoint: v.endpointOf(host), host: host})
+	}
+
+	if v.tty {
+		v.redraw(time.Now())
+	} else {
+		for _, r := range v.rows {
+			fmt.Println(v.line(r, r.host.State))
+		}
+	}
+
+	for ev := range events {
+		batch := pending(ev, events)
+		for _, r := range v.rows {
+			r.changed = false
+		}
+		for _, ev := range batch {
+			v.update(ev)
+		}
+		if v.tty {
+			v.redraw(batch[len(batch)-1].time)
+		}
+	}
+
+	return 0
+}
+
+// endpointOf returns the index of the endpoint an entry was listed from
+func (v *view) endpointOf(host *Host) int {
+	for i, ep := range v.endpoints {
+		if host.Host == ep.Name() {
+			return i
+		}
+	}
+	return 0
+}
+
+// find returns the row of a container, or -1
+func (v *view) find(endpoint int, ID string) int {
+	for i, r := range v.rows {
+		if r.endpoint == endpoint && r.host.ID == ID {
+			return i
+		}
+	}
+	return -1
+}
+
+// matches tells whether a container is selected, like query does; the
+// patterns are only checked for containers not listed yet, so that a renamed
+// container stays listed, while the selector and --problems are re-evaluated
+// on every change
+func (v *view) matches(inspectData *docker.Container, listed bool) (bool, error) {
+	if v.sel != nil && !v.sel.Match(inspectData) {
+		return false, nil
+	}
+	if *problems && len(getProblems(inspectData)) == 0 {
+		return false, nil
+	}
+	if listed || len(v.patterns) == 0 {
+		return true, nil
+	}
+
+	container := docker.APIContainers{
+		ID:    inspectData.ID,
+		Names: []string{"/" + inspectData.Name},
+	}
+	if inspectData.Config != nil {
+		container.Image = inspectData.Config.Image
+		container.Labels = inspectData.Config.Labels
+	}
+	matching, err := resolver.New([]docker.APIContainers{container}).ResolveAll(v.patterns)
+	if err != nil {
+		return false, err
+	}
+	return len(matching) != 0, nil
+}
+
+// update refreshes the row of the container of an event, adding or removing
+// it as needed, and reports the change when not redrawing in place
+func (v *view) update(ev containerEvent) {
+	i := v.find(ev.endpoint, ev.ID)
+
+	var inspectData *docker.Container
+	if ev.action != "destroy" {
+		var err error
+		inspectData, err = v.clients[ev.endpoint].InspectContainer(ev.ID)
+		if _, ok := err.(*docker.NoSuchContainer); ok {
+			inspectData = nil
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-hosts: about '%s': %s\n", ev.ID, err)
+			return
+		} else {
+			// like for listed containers
+			inspectData.Name = strings.TrimPrefix(inspectData.Name, "/")
+		}
+	}
+
+	selected := false
+	if inspectData != nil {
+		var err error
+		selected, err = v.matches(inspectData, i != -1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker-hosts: %s\n", err)
+			return
+		}
+	}
+
+	if !selected {
+		if i == -1 {
+			return
+		}
+		r := v.rows[i]
+		v.rows = append(v.rows[:i], v.rows[i+1:]...)
+		v.report(ev, "-", r, r.host.State)
+		return
+	}
+
+	var hostName string
+	if len(v.endpoints) > 1 {
+		hostName = v.endpoints[ev.endpoint].Name()
+	}
+	host, err := getHost(v.clients[ev.endpoint], inspectData)
+	if err != nil {
+		warn(hostName, "about '%s': cannot read hostname: %s", inspectData.Name, err)
+	}
+	host.Host = hostName
+
+	if i == -1 {
+		r := &row{endpoint: ev.endpoint, host: host, changed: true}
+		v.rows = append(v.rows, r)
+		v.report(ev, "+", r, host.State)
+		return
+	}
+
+	// only changes of the shown columns are reported
+	r := v.rows[i]
+	if strings.Join(v.cells(r, r.host.State), "\x00") == strings.Join(v.cells(&row{host: host}, host.State), "\x00") {
+		return
+	}
+	r.previous = r.host.State
+	r.host = host
+	r.changed = true
+	v.report(ev, "~", r, transition(r.previous, host.State))
+}
+
+// transition formats a state change, e.g. 'Running -> Exit (137)'
+func transition(previous, state string) string {
+	if previous == "" || previous == state {
+		return state
+	}
+	return previous + " -> " + state
+}
+
+// report prints a change line, unless redrawing in place
+func (v *view) report(ev containerEvent, sign string, r *row, state string) {
+	if v.tty {
+		return
+	}
+	fmt.Printf("%s %s %s\n", ev.time.Format("15:04:05"), sign, v.line(r, state))
+}
+
+// cells renders a row with the selected columns and the given state
+func (v *view) cells(r *row, state string) []string {
+	host := *r.host
+	host.State = state
+	result := cells(&host, selectedColumns)
+	if len(v.endpoints) > 1 {
+		result = append([]string{host.Host}, result...)
+	}
+	return result
+}
+
+// line formats a row like the default output of docker-hosts, with the given
+// state, aligned with the rows being shown
+func (v *view) line(r *row, state string) string {
+	var rows [][]string
+	for _, r := range v.rows {
+		rows = append(rows, v.cells(r, r.host.State))
+	}
+	rows = append(rows, v.cells(r, state))
+
+	lines := output.Align(rows)
+	return lines[len(lines)-1]
+}
+
+// redraw clears the terminal and shows all rows, highlighting those changed
+// by the last events
+func (v *view) redraw(t time.Time) {
+	rows := make([][]string, len(v.rows))
+	for i, r := range v.rows {
+		if r.changed {
+			rows[i] = v.cells(r, transition(r.previous, r.host.State))
+		} else {
+			rows[i] = v.cells(r, r.host.State)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&b, "docker-hosts: %d containers, updated at %s\n\n", len(v.rows), t.Format("15:04:05"))
+	for i, line := range output.Align(rows) {
+		if v.rows[i].changed {
+			b.WriteString("\033[1m" + line + "\033[0m\n")
+		} else {
+			b.WriteString(line + "\n")
+		}
+	}
+	os.Stdout.WriteString(b.String())
+}
@@ -0,0 +1,139 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package images implements docker-images.
+package images
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/cliplugin"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/docker-cli-tools/pkg/dockertools"
+	"github.com/gdm85/go-dockerclient"
+	"github.com/gdm85/goopt"
+	"os"
+	"strings"
+)
+
+// Image is an entry of docker-images output, one per name of each image
+type Image struct {
+	// daemon the image is stored on, set only when querying several
+	Host    string `json:",omitempty"`
+	ID      string
+	Name    string
+	Created int64
+	Size    int64
+}
+
+var imageFields = []string{"ID", "Name", "Created", "Size"}
+
+func showUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: docker-images [options] [partial-match]")
+	fmt.Fprintln(os.Stderr, "Use --help for the list of options")
+	fmt.Fprintln(os.Stderr, "docker-images is part of docker-cli-tools and licensed under GNU GPLv2")
+}
+
+func newImage(host string, image *docker.APIImages, name string) *Image {
+	return &Image{Host: host, ID: image.ID, Name: name, Created: image.Created, Size: image.Size}
+}
+
+const description = "Provide a terse output of all existing images, in name:tag format or ID when a name is not available."
+
+// Main runs docker-images with the command line found in os.Args
+func Main() {
+	cliplugin.Handle(description)
+
+	// if no arguments specified, show help and exit with failure
+	if len(os.Args) == 2 && os.Args[1] == "-h" {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	goopt.Description = func() string {
+		return description
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "docker-images"
+	dockerenv.RegisterFlags()
+	output.RegisterFlags(imageFields)
+	goopt.Parse(nil)
+
+	if len(goopt.Args) > 1 {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	out, err := output.NewWriter(imageFields, func(entry interface{}) string {
+		return entry.(*Image).Name
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-images: %s\n", err)
+		os.Exit(1)
+	}
+
+	endpoints, err := dockerenv.Endpoints()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-images: %s\n", err)
+		os.Exit(1)
+	}
+
+	status := fanout.Run("docker-images", endpoints, out, func(host string, client *docker.Client) ([]interface{}, error) {
+		return query(host, client, goopt.Args)
+	})
+	if status != 0 {
+		os.Exit(status)
+	}
+}
+
+// query lists the images of a daemon, only those with a name containing
+// the pattern if one is specified
+func query(host string, client *docker.Client, args []string) ([]interface{}, error) {
+	// fetch all images data
+	allImages, err := client.ListImages(docker.ListImagesOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []interface{}
+	if len(args) == 1 {
+		// show images that have at least a partial pattern match
+		pattern := args[0]
+
+		for _, image := range allImages {
+			for _, name := range dockertools.GetNamesOrIDs(&image) {
+				if strings.Contains(name, pattern) {
+					entries = append(entries, newImage(host, &image, name))
+				}
+			}
+		}
+	} else {
+		// show all images
+		for _, image := range allImages {
+			for _, name := range dockertools.GetNamesOrIDs(&image) {
+				entries = append(entries, newImage(host, &image, name))
+			}
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,461 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package ipv4 implements docker-ipv4.
+package ipv4
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/cliplugin"
+	"github.com/gdm85/docker-cli-tools/internal/dockerenv"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/internal/output"
+	"github.com/gdm85/docker-cli-tools/internal/resolver"
+	"github.com/gdm85/docker-cli-tools/pkg/dockertools"
+	"github.com/gdm85/go-dockerclient"
+	"github.com/gdm85/goopt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Address is an entry of docker-ipv4 output
+type Address struct {
+	// daemon the container runs on, set only when querying several
+	Host        string `json:",omitempty"`
+	ID          string
+	Name        string
+	Network     string
+	Family      string // inet or inet6
+	Scope       string // global, or link for IPv6 link-local addresses
+	IPAddress   string
+	IPPrefixLen int
+	Gateway     string
+	MacAddress  string
+	State       string
+}
+
+var addressFields = []string{"ID", "Name", "Network", "Family", "Scope", "IPAddress", "IPPrefixLen", "Gateway", "MacAddress", "State"}
+
+var (
+	network       *string
+	long          *bool
+	allNetworks   *bool
+	family        *string
+	ipv6          *bool
+	allFamilies   *bool
+	waitReady     *bool
+	timeoutValue  *string
+	reverseLookup *bool
+	// set by Main from the options above
+	inet, inet6 bool
+	timeout     time.Duration
+)
+
+// interval between two checks with --wait, unless events come first
+const pollInterval = 500 * time.Millisecond
+
+// pendingPatterns tracks the patterns not found yet on any daemon with
+// --wait, so that each daemon keeps waiting for containers that might be
+// created on another one
+type pendingPatterns struct {
+	mu   sync.Mutex
+	left map[string]bool
+	done chan struct{}
+}
+
+func newPendingPatterns(patterns []string) *pendingPatterns {
+	p := &pendingPatterns{left: map[string]bool{}, done: make(chan struct{})}
+	for _, pattern := range patterns {
+		p.left[pattern] = true
+	}
+	if len(p.left) == 0 {
+		close(p.done)
+	}
+	return p
+}
+
+// found marks patterns as found, waking up all daemons when none is left
+func (p *pendingPatterns) found(patterns []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.left) == 0 {
+		return
+	}
+	for _, pattern := range patterns {
+		delete(p.left, pattern)
+	}
+	if len(p.left) == 0 {
+		close(p.done)
+	}
+}
+
+func showUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: docker-ipv4 [options] container1 [container2] [...] [containerN]")
+	fmt.Fprintln(os.Stderr, "       docker-ipv4 --reverse [options] address1 [address2/prefix] [...] [addressN]")
+	fmt.Fprintln(os.Stderr, "Use --help for the list of options")
+	fmt.Fprintln(os.Stderr, "docker-ipv4 is part of docker-cli-tools and licensed under GNU GPLv2")
+}
+
+const description = "Find IPv4 internal Docker network address of one or multiple containers."
+
+// registerFlags adds the options of docker-ipv4 to the command line parser
+func registerFlags() {
+	network = goopt.String([]string{"--network"}, "", "only show the address on this network; it is an error if a container has none")
+	long = goopt.Flag([]string{"-l", "--long"}, []string{}, "show name, network, address/prefix, gateway and MAC address on every network", "")
+	allNetworks = goopt.Flag([]string{"--all-networks"}, []string{}, "show the addresses on every network instead of the primary one", "")
+	family = goopt.String([]string{"--family"}, "4", "address family to show, 4 or 6 (also inet or inet6)")
+	ipv6 = goopt.Flag([]string{"-6"}, []string{}, "show IPv6 global and link-local addresses; same as --family 6", "")
+	allFamilies = goopt.Flag([]string{"--all-families"}, []string{}, "show both IPv4 and IPv6 addresses", "")
+	waitReady = goopt.Flag([]string{"--wait"}, []string{}, "wait until all containers are running and have an address; exit status is 3 on timeout and 4 if a container exits first", "")
+	timeoutValue = goopt.String([]string{"--timeout"}, "30s", "maximum time to wait with --wait")
+	reverseLookup = goopt.Flag([]string{"--reverse"}, []string{}, "arguments are IPv4/IPv6 addresses or CIDRs, show the containers holding them or that last held them", "")
+}
+
+// selectFamilies sets inet and inet6 from the command line options
+func selectFamilies() error {
+	switch {
+	case *allFamilies:
+		inet, inet6 = true, true
+	case *ipv6:
+		inet6 = true
+	default:
+		switch *family {
+		case "4", "inet", "ipv4":
+			inet = true
+		case "6", "inet6", "ipv6":
+			inet6 = true
+		default:
+			return fmt.Errorf("invalid address family '%s'", *family)
+		}
+	}
+	return nil
+}
+
+// familyName describes the selected families in error messages
+func familyName() string {
+	switch {
+	case inet && inet6:
+		return "IP"
+	case inet6:
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// newAddresses returns the entries of a network endpoint for the given families
+func newAddresses(container *docker.Container, address dockertools.NetworkAddress, inet, inet6 bool) []*Address {
+	var result []*Address
+	add := func(family, scope, ip string, prefixLen int, gateway string) {
+		if ip == "" {
+			return
+		}
+		result = append(result, &Address{
+			ID:          container.ID,
+			Name:        strings.TrimPrefix(container.Name, "/"),
+			Network:     address.Network,
+			Family:      family,
+			Scope:       scope,
+			IPAddress:   ip,
+			IPPrefixLen: prefixLen,
+			Gateway:     gateway,
+			MacAddress:  address.MacAddress,
+			State:       dockertools.GetState(container),
+		})
+	}
+
+	if inet {
+		add("inet", "global", address.IPAddress, address.IPPrefixLen, address.Gateway)
+	}
+	if inet6 {
+		add("inet6", "global", address.GlobalIPv6Address, address.GlobalIPv6PrefixLen, address.IPv6Gateway)
+		add("inet6", "link", address.LinkLocalIPv6Address, address.LinkLocalIPv6PrefixLen, "")
+	}
+	return result
+}
+
+// orDash keeps columns of the long format aligned when a value is missing
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func plain(entry interface{}) string {
+	address := entry.(*Address)
+	if *long {
+		return fmt.Sprintf("%s %s %s/%d %s %s", address.Name, address.Network, address.IPAddress, address.IPPrefixLen, orDash(address.Gateway), orDash(address.MacAddress))
+	}
+	if *reverseLookup {
+		return fmt.Sprintf("%s %s %s %s", address.IPAddress, address.Name, address.Network, address.State)
+	}
+	return address.IPAddress
+}
+
+// Main runs docker-ipv4 with the command line found in os.Args
+func Main() {
+	cliplugin.Handle(description)
+
+	// if no arguments specified, show help and exit with failure
+	if len(os.Args) == 1 || (len(os.Args) == 2 && os.Args[1] == "-h") {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	goopt.Description = func() string {
+		return description
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "docker-ipv4"
+	dockerenv.RegisterFlags()
+	registerFlags()
+	output.RegisterFlags(addressFields)
+	goopt.Parse(nil)
+
+	if len(goopt.Args) == 0 {
+		showUsage()
+		os.Exit(1)
+		return
+	}
+
+	err := selectFamilies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-ipv4: %s\n", err)
+		os.Exit(1)
+	}
+
+	timeout, err = time.ParseDuration(*timeoutValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-ipv4: invalid --timeout: %s\n", err)
+		os.Exit(1)
+	}
+
+	var nets []*net.IPNet
+	if *reverseLookup {
+		if *waitReady {
+			fmt.Fprintf(os.Stderr, "docker-ipv4: --wait cannot be used with --reverse\n")
+			os.Exit(1)
+		}
+
+		for _, arg := range goopt.Args {
+			ipNet, err := parseQuery(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "docker-ipv4: %s\n", err)
+				os.Exit(1)
+			}
+			nets = append(nets, ipNet)
+		}
+	}
+
+	out, err := output.NewWriter(addressFields, plain)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-ipv4: %s\n", err)
+		os.Exit(1)
+	}
+
+	endpoints, err := dockerenv.Endpoints()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docker-ipv4: %s\n", err)
+		os.Exit(1)
+	}
+
+	// with several daemons a container is expected to run on only one of
+	// them, thus patterns are missing only when no daemon has a match
+	var mu sync.Mutex
+	found := map[string]bool{}
+	pending := newPendingPatterns(goopt.Args)
+	status := fanout.Run("docker-ipv4", endpoints, out, func(host string, client *docker.Client) ([]interface{}, error) {
+		var entries []interface{}
+		var matched []string
+		var err error
+		if *reverseLookup {
+			entries, matched, err = reverse(host, client, goopt.Args, nets)
+		} else if *waitReady {
+			entries, matched, err = wait(host, client, goopt.Args, pending)
+		} else {
+			entries, matched, _, err = query(host, client, goopt.Args, false)
+		}
+		mu.Lock()
+		for _, pattern := range matched {
+			found[pattern] = true
+		}
+		mu.Unlock()
+		return entries, err
+	})
+	if *reverseLookup {
+		for _, arg := range goopt.Args {
+			if !found[arg] {
+				fmt.Fprintf(os.Stderr, "docker-ipv4: no container has address '%s'\n", arg)
+				status = 2
+			}
+		}
+	} else if len(endpoints) > 1 {
+		for _, pattern := range goopt.Args {
+			if found[pattern] {
+				continue
+			}
+			if *waitReady {
+				fmt.Fprintf(os.Stderr, "docker-ipv4: timed out after %s: cannot find '%s'\n", timeout, pattern)
+				status = 3
+			} else {
+				fmt.Fprintf(os.Stderr, "docker-ipv4: cannot find '%s'\n", pattern)
+				status = 2
+			}
+		}
+	}
+	if status != 0 {
+		os.Exit(status)
+	}
+}
+
+// wait repeats query until it succeeds and all patterns were found on some
+// daemon, waking up on events of the daemon or every pollInterval when they
+// are not available; failures that cannot be waited out are returned
+// immediately
+func wait(host string, client *docker.Client, patterns []string, pending *pendingPatterns) ([]interface{}, []string, error) {
+	deadline := time.Now().Add(timeout)
+
+	wake := make(chan struct{}, 1)
+	events := make(chan *docker.APIEvents, 16)
+	if client.AddEventListener(events) == nil {
+		defer client.RemoveEventListener(events)
+		go func() {
+			for _ = range events {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	done := pending.done
+	for {
+		entries, matched, retry, err := query(host, client, patterns, true)
+		if err != nil && !retry {
+			// other daemons need not wait for these patterns either
+			pending.found(matched)
+			return entries, matched, err
+		}
+		if err == nil {
+			pending.found(matched)
+			select {
+			case <-done:
+				return entries, matched, nil
+			default:
+			}
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			if err == nil {
+				// patterns found on no daemon are reported by Main
+				return entries, matched, nil
+			}
+			return nil, matched, fanout.Errorf(3, "timed out after %s: %s", timeout, err)
+		}
+		if remaining > pollInterval {
+			remaining = pollInterval
+		}
+
+		select {
+		case <-wake:
+		case <-done:
+			// only failures of this daemon are left to wait out
+			done = nil
+		case <-time.After(remaining):
+		}
+	}
+}
+
+// query lists the addresses of the containers matching the patterns, which
+// must all match when host is empty; matched patterns are returned as well.
+// When waiting, failures that may go away later are marked as pending and
+// containers that already exited fail with exit status 4
+func query(host string, client *docker.Client, patterns []string, waiting bool) (entries []interface{}, matched []string, pending bool, err error) {
+	// fetch all containers data
+	allContainers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	r := resolver.New(allContainers)
+	for _, pattern := range patterns {
+		matching, err := r.Resolve(pattern)
+		if err != nil {
+			return entries, matched, false, &fanout.Error{Status: 2, Err: err}
+		}
+		if len(matching) == 0 {
+			if host != "" {
+				continue
+			}
+			// the container might not be created yet
+			return entries, matched, true, fanout.Errorf(2, "cannot find '%s'", pattern)
+		}
+		matched = append(matched, pattern)
+
+		for _, match := range matching {
+			// pull new inspect data from API
+			container, err := client.InspectContainer(match.ID)
+			if err != nil {
+				return entries, matched, false, fanout.Errorf(2, "cannot find '%s': %s", pattern, err)
+			}
+
+			if !container.State.Running {
+				// created and restarting containers are expected to start
+				pending = container.State.Restarting || container.State.StartedAt.IsZero()
+				if waiting && !pending {
+					return entries, matched, false, fanout.Errorf(4, "container '%s' exited before being ready", container.Name[1:])
+				}
+				return entries, matched, pending, fanout.Errorf(2, "container '%s' is not running", container.Name[1:])
+			}
+
+			// unless asked for, only the primary address is shown, or the
+			// first one when there is none on the primary network
+			everyNetwork := *long || *allNetworks || *network != ""
+			found := false
+			for _, address := range dockertools.GetAddresses(container) {
+				if *network != "" && address.Network != *network {
+					continue
+				}
+				if found && !everyNetwork {
+					break
+				}
+
+				for _, entry := range newAddresses(container, address, inet, inet6) {
+					entry.Host = host
+					entries = append(entries, entry)
+					found = true
+				}
+			}
+			if !found {
+				if *network != "" {
+					return entries, matched, true, fanout.Errorf(2, "container '%s' has no %s address on network '%s'", container.Name[1:], familyName(), *network)
+				}
+				return entries, matched, true, fanout.Errorf(2, "container '%s' has no %s address", container.Name[1:], familyName())
+			}
+		}
+	}
+
+	return entries, matched, false, nil
+}
@@ -0,0 +1,160 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package ipv4
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/fanout"
+	"github.com/gdm85/docker-cli-tools/internal/inspect"
+	"github.com/gdm85/docker-cli-tools/pkg/dockertools"
+	"github.com/gdm85/go-dockerclient"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// parseQuery parses an address or a CIDR given to --reverse
+func parseQuery(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address '%s'", s)
+		}
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address '%s'", s)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bits = 8 * net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// holder is a container with an address matching one of the queries
+type holder struct {
+	query    int
+	address  *Address
+	running  bool
+	finished time.Time
+}
+
+// byRecency sorts holders in query order, running containers first and then
+// the most recently exited ones
+type byRecency []*holder
+
+func (s byRecency) Len() int {
+	return len(s)
+}
+func (s byRecency) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+func (s byRecency) Less(i, j int) bool {
+	a, b := s[i], s[j]
+	if a.query != b.query {
+		return a.query < b.query
+	}
+	if a.running != b.running {
+		return a.running
+	}
+	return a.finished.After(b.finished)
+}
+
+// reverse lists the containers holding an address in any of the queries, or
+// that last held it according to the inspect data of stopped containers;
+// queries with at least one match are returned as well
+func reverse(host string, client *docker.Client, queries []string, nets []*net.IPNet) ([]interface{}, []string, error) {
+	allContainers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// addresses are only found in inspect data
+	inspectCache := inspect.NewCache(client)
+	IDs := make([]string, len(allContainers))
+	for i, container := range allContainers {
+		IDs[i] = container.ID
+	}
+	inspectCache.Prefetch(IDs, inspect.DefaultParallel)
+
+	// a container removed meanwhile is skipped, one that cannot be inspected
+	// only causes a warning
+	var holders []*holder
+	failed := 0
+	for _, ID := range IDs {
+		container, err := inspectCache.Get(ID)
+		if err != nil {
+			if _, ok := err.(*docker.NoSuchContainer); ok {
+				continue
+			}
+			warn(host, "about '%s': %s", ID, err)
+			failed++
+			continue
+		}
+
+		for _, address := range dockertools.GetAddresses(container) {
+			if *network != "" && address.Network != *network {
+				continue
+			}
+
+			for _, entry := range newAddresses(container, address, true, true) {
+				ip := net.ParseIP(entry.IPAddress)
+				for i, ipNet := range nets {
+					if ip == nil || !ipNet.Contains(ip) {
+						continue
+					}
+					entry.Host = host
+					holders = append(holders, &holder{query: i, address: entry, running: container.State.Running, finished: container.State.FinishedAt})
+				}
+			}
+		}
+	}
+	sort.Sort(byRecency(holders))
+
+	var entries []interface{}
+	var matched []string
+	seen := map[int]bool{}
+	for _, h := range holders {
+		entries = append(entries, h.address)
+		if !seen[h.query] {
+			seen[h.query] = true
+			matched = append(matched, queries[h.query])
+		}
+	}
+
+	if failed != 0 {
+		return entries, matched, fanout.Errorf(3, "%d of %d containers could not be inspected", failed, len(IDs))
+	}
+	return entries, matched, nil
+}
+
+// warn reports a failure about a single container on stderr
+func warn(host, format string, a ...interface{}) {
+	if host != "" {
+		format = host + ": " + format
+	}
+	fmt.Fprintf(os.Stderr, "docker-ipv4: warning: "+format+"\n", a...)
+}
@@ -0,0 +1,193 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+// Package dockertools exposes the logic behind docker-cli-tools for use in
+// other Go programs: naming of containers and images, derivation of container
+// states and mapping of host processes to their containers.
+//
+// Functions that query the daemon accept a Client, which *docker.Client
+// satisfies, so that they can be exercised against a fake implementation.
+package dockertools
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gdm85/go-dockerclient"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Client is the subset of the Docker API client used by this package
+type Client interface {
+	InspectContainer(id string) (*docker.Container, error)
+}
+
+// trimName removes the leading slash of names in inspect data
+func trimName(name string) string {
+	return strings.TrimPrefix(name, "/")
+}
+
+// NameOrHostname formats a container name followed by its hostname in
+// parenthesis, when the latter is set and different from the name
+func NameOrHostname(name, hostname string) string {
+	if hostname != "" && hostname != name {
+		return fmt.Sprintf("%s (%s)", name, hostname)
+	}
+	return name
+}
+
+// GetNameOrHostname inspects a container and returns its name formatted by
+// NameOrHostname
+func GetNameOrHostname(client Client, id string) (string, error) {
+	container, err := client.InspectContainer(id)
+	if err != nil {
+		return "", err
+	}
+
+	return NameOrHostname(trimName(container.Name), ConfigHostname(container)), nil
+}
+
+// GetState returns "Running", "Paused", "Restarting (N)", "Dead" or "Exit (N)"
+// with N the exit code; the health status is appended to "Running", e.g.
+// "Running (unhealthy)", and OOM kills to "Exit (N)", e.g. "Exit (137, OOM-killed)"
+func GetState(container *docker.Container) string {
+	state := container.State
+	switch {
+	case state.Dead:
+		return "Dead"
+	case state.Restarting:
+		return fmt.Sprintf("Restarting (%d)", state.ExitCode)
+	case state.Running && state.Paused:
+		return "Paused"
+	case state.Running:
+		if status, _ := GetHealth(container); status != "" {
+			return "Running (" + status + ")"
+		}
+		return "Running"
+	case state.OOMKilled:
+		return fmt.Sprintf("Exit (%d, OOM-killed)", state.ExitCode)
+	}
+	return fmt.Sprintf("Exit (%d)", state.ExitCode)
+}
+
+// GetHealth returns the health status of a container, "starting", "healthy"
+// or "unhealthy", and the first line of the output of its last probe; the
+// status is empty for containers without health check
+func GetHealth(container *docker.Container) (string, string) {
+	health := container.State.Health
+	if health.Status == "" || health.Status == "none" {
+		return "", ""
+	}
+	if len(health.Log) == 0 {
+		return health.Status, ""
+	}
+
+	output := strings.TrimSpace(health.Log[len(health.Log)-1].Output)
+	return health.Status, strings.SplitN(output, "\n", 2)[0]
+}
+
+// RestartLoop tells whether a container is restarting over and over: it was
+// restarted at least threshold times and is restarting right now or was last
+// started less than window before now
+func RestartLoop(container *docker.Container, threshold int, window time.Duration, now time.Time) bool {
+	if container.RestartCount < threshold {
+		return false
+	}
+	if container.State.Restarting {
+		return true
+	}
+	started := container.State.StartedAt
+	return !started.IsZero() && now.Sub(started) < window
+}
+
+// GetProblems returns why a container needs attention, if it does: "unhealthy",
+// "oom-killed", "exit N" for a non-zero exit code, "dead" and "restart loop
+// (N restarts)" as detected by RestartLoop
+func GetProblems(container *docker.Container, threshold int, window time.Duration, now time.Time) []string {
+	var problems []string
+	state := container.State
+	if status, _ := GetHealth(container); status == "unhealthy" {
+		problems = append(problems, "unhealthy")
+	}
+	if state.OOMKilled {
+		problems = append(problems, "oom-killed")
+	}
+	if !state.Running && !state.Dead && state.ExitCode != 0 {
+		problems = append(problems, fmt.Sprintf("exit %d", state.ExitCode))
+	}
+	if state.Dead {
+		problems = append(problems, "dead")
+	}
+	if RestartLoop(container, threshold, window, now) {
+		problems = append(problems, fmt.Sprintf("restart loop (%d restarts)", container.RestartCount))
+	}
+	return problems
+}
+
+// GetContainer returns the ID of the container a process runs in, as found
+// in its perf_event cgroup, or an empty string for processes of the host;
+// procRoot is the mount point of the host procfs, usually /proc
+func GetContainer(procRoot string, pid int) (string, error) {
+	inFile, err := os.Open(filepath.Join(procRoot, fmt.Sprint(pid), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	defer inFile.Close()
+
+	scanner := bufio.NewScanner(inFile)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) == 3 && parts[1] == "perf_event" {
+			parts = strings.SplitN(parts[2], "/", 3)
+			if len(parts) == 3 && parts[1] == "docker" {
+				return parts[2], nil
+			}
+			break
+		}
+	}
+	return "", scanner.Err()
+}
+
+// GetContainerName inspects a container and returns its name
+func GetContainerName(client Client, id string) (string, error) {
+	container, err := client.InspectContainer(id)
+	if err != nil {
+		return "", err
+	}
+
+	return trimName(container.Name), nil
+}
+
+// GetNamesOrIDs returns all the name:tag names of an image, with its ID in
+// place of untagged names
+func GetNamesOrIDs(image *docker.APIImages) []string {
+	buffer := []string{}
+	for _, name := range image.RepoTags {
+		if name == "<none>:<none>" {
+			buffer = append(buffer, image.ID)
+		} else {
+			buffer = append(buffer, name)
+		}
+	}
+
+	return buffer
+}
@@ -0,0 +1,209 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package dockertools
+
+import (
+	"fmt"
+	"github.com/gdm85/go-dockerclient"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClient serves inspect data from a map of containers by ID
+type fakeClient map[string]*docker.Container
+
+func (c fakeClient) InspectContainer(id string) (*docker.Container, error) {
+	container, ok := c[id]
+	if !ok {
+		return nil, &docker.NoSuchContainer{ID: id}
+	}
+	return container, nil
+}
+
+var now = time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+
+func health(status, output string) docker.Health {
+	return docker.Health{Status: status, Log: []docker.HealthCheck{{Output: output}}}
+}
+
+func TestGetState(t *testing.T) {
+	tests := []struct {
+		name  string
+		state docker.State
+		want  string
+	}{
+		{"running", docker.State{Running: true}, "Running"},
+		{"healthy", docker.State{Running: true, Health: health("healthy", "ok")}, "Running (healthy)"},
+		{"no health check", docker.State{Running: true, Health: docker.Health{Status: "none"}}, "Running"},
+		{"paused", docker.State{Running: true, Paused: true}, "Paused"},
+		{"restarting", docker.State{Running: true, Restarting: true, ExitCode: 1}, "Restarting (1)"},
+		{"dead", docker.State{Dead: true, ExitCode: 2}, "Dead"},
+		{"exited", docker.State{ExitCode: 0}, "Exit (0)"},
+		{"oom-killed", docker.State{OOMKilled: true, ExitCode: 137}, "Exit (137, OOM-killed)"},
+	}
+	for _, test := range tests {
+		got := GetState(&docker.Container{State: test.state})
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGetHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		health         docker.Health
+		status, output string
+	}{
+		{"none", docker.Health{}, "", ""},
+		{"no probe yet", docker.Health{Status: "starting"}, "starting", ""},
+		{"first line", health("unhealthy", "\ncurl: (7) refused\nretrying\n"), "unhealthy", "curl: (7) refused"},
+	}
+	for _, test := range tests {
+		status, output := GetHealth(&docker.Container{State: docker.State{Health: test.health}})
+		if status != test.status || output != test.output {
+			t.Errorf("%s: got %q, %q, want %q, %q", test.name, status, output, test.status, test.output)
+		}
+	}
+}
+
+func TestGetProblems(t *testing.T) {
+	tests := []struct {
+		name      string
+		container docker.Container
+		want      []string
+	}{
+		{"healthy", docker.Container{State: docker.State{Running: true, Health: health("healthy", "")}}, nil},
+		{"exited cleanly", docker.Container{State: docker.State{ExitCode: 0}}, nil},
+		{"unhealthy", docker.Container{State: docker.State{Running: true, Health: health("unhealthy", "")}}, []string{"unhealthy"}},
+		{"failed", docker.Container{State: docker.State{ExitCode: 1}}, []string{"exit 1"}},
+		{"oom-killed", docker.Container{State: docker.State{OOMKilled: true, ExitCode: 137}}, []string{"oom-killed", "exit 137"}},
+		{"dead", docker.Container{State: docker.State{Dead: true, ExitCode: 1}}, []string{"dead"}},
+		{"restart loop", docker.Container{RestartCount: 5, State: docker.State{Restarting: true, ExitCode: 1}}, []string{"exit 1", "restart loop (5 restarts)"}},
+		{"restarted recently", docker.Container{RestartCount: 3, State: docker.State{Running: true, StartedAt: now.Add(-time.Minute)}}, []string{"restart loop (3 restarts)"}},
+		{"restarted long ago", docker.Container{RestartCount: 3, State: docker.State{Running: true, StartedAt: now.Add(-time.Hour)}}, nil},
+		{"few restarts", docker.Container{RestartCount: 2, State: docker.State{Restarting: true}}, nil},
+	}
+	for _, test := range tests {
+		got := GetProblems(&test.container, 3, 10*time.Minute, now)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGetContainer(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "dockertools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(procRoot)
+
+	cgroups := map[int]string{
+		1:  "4:perf_event:/\n3:cpu:/\n",
+		2:  "5:cpu:/docker/a1b2c3\n4:perf_event:/docker/a1b2c3d4e5f6\n",
+		3:  "4:perf_event:/user.slice\n",
+		4:  "0::/system.slice/docker.service\n",
+		10: "4:perf_event:/docker\n",
+	}
+	for pid, cgroup := range cgroups {
+		dir := filepath.Join(procRoot, fmt.Sprint(pid))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroup), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		pid  int
+		want string
+	}{
+		{1, ""},
+		{2, "a1b2c3d4e5f6"},
+		{3, ""},
+		{4, ""},
+		{10, ""},
+	}
+	for _, test := range tests {
+		got, err := GetContainer(procRoot, test.pid)
+		if err != nil {
+			t.Errorf("pid %d: %s", test.pid, err)
+		} else if got != test.want {
+			t.Errorf("pid %d: got %q, want %q", test.pid, got, test.want)
+		}
+	}
+
+	if _, err := GetContainer(procRoot, 99); !os.IsNotExist(err) {
+		t.Errorf("pid 99: got error %v, want a not-exist error", err)
+	}
+}
+
+func TestGetNamesOrIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		image docker.APIImages
+		want  []string
+	}{
+		{"tagged", docker.APIImages{ID: "sha256:1", RepoTags: []string{"nginx:1.25", "nginx:latest"}}, []string{"nginx:1.25", "nginx:latest"}},
+		{"untagged", docker.APIImages{ID: "sha256:2", RepoTags: []string{"<none>:<none>"}}, []string{"sha256:2"}},
+		{"no tags", docker.APIImages{ID: "sha256:3"}, []string{}},
+	}
+	for _, test := range tests {
+		got := GetNamesOrIDs(&test.image)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGetNameOrHostname(t *testing.T) {
+	client := fakeClient{
+		"1": {Name: "/web-1", Config: &docker.Config{Hostname: "web-1"}},
+		"2": {Name: "/db", Config: &docker.Config{Hostname: "postgres", Domainname: "example.com"}},
+		"3": {Name: "/job"},
+	}
+	tests := []struct {
+		id, want string
+	}{
+		{"1", "web-1"},
+		{"2", "db (postgres.example.com)"},
+		{"3", "job"},
+	}
+	for _, test := range tests {
+		got, err := GetNameOrHostname(client, test.id)
+		if err != nil {
+			t.Errorf("%s: %s", test.id, err)
+		} else if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.id, got, test.want)
+		}
+	}
+
+	if _, err := GetContainerName(client, "4"); err == nil {
+		t.Errorf("4: got no error for a missing container")
+	} else if _, ok := err.(*docker.NoSuchContainer); !ok {
+		t.Errorf("4: got %T, want *docker.NoSuchContainer", err)
+	}
+}
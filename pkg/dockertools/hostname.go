@@ -0,0 +1,95 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package dockertools
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"github.com/gdm85/go-dockerclient"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ArchiveClient is the subset of the Docker API client used by FetchHostname
+type ArchiveClient interface {
+	DownloadFromContainer(id string, opts docker.DownloadFromContainerOptions) error
+}
+
+// ConfigHostname returns the hostname a container was created with, followed
+// by its domain name when set
+func ConfigHostname(container *docker.Container) string {
+	if container.Config == nil {
+		return ""
+	}
+	if container.Config.Domainname == "" {
+		return container.Config.Hostname
+	}
+	return container.Config.Hostname + "." + container.Config.Domainname
+}
+
+// GetHostname returns the hostname of a container as written in its hostname
+// file, or an empty string when the container has no such file; the file is
+// on the filesystem of the daemon host, and usually readable only by root
+func GetHostname(container *docker.Container) (string, error) {
+	if len(container.HostnamePath) == 0 {
+		return "", nil
+	}
+
+	bytes, err := ioutil.ReadFile(container.HostnamePath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bytes)), nil
+}
+
+// FetchHostname returns the content of /etc/hostname of a container, downloaded
+// through the archive API of the daemon
+func FetchHostname(client ArchiveClient, id string) (string, error) {
+	var archive bytes.Buffer
+	err := client.DownloadFromContainer(id, docker.DownloadFromContainerOptions{
+		Path:         "/etc/hostname",
+		OutputStream: &archive,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	tr := tar.NewReader(&archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("no /etc/hostname in the archive of container %s", id)
+		}
+		if err != nil {
+			return "", err
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+}
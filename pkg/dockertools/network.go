@@ -0,0 +1,113 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package dockertools
+
+import (
+	"github.com/gdm85/go-dockerclient"
+	"sort"
+)
+
+// NetworkAddress is the endpoint of a container on one of its networks
+type NetworkAddress struct {
+	Network             string
+	IPAddress           string
+	IPPrefixLen         int
+	Gateway             string
+	MacAddress          string
+	GlobalIPv6Address   string
+	GlobalIPv6PrefixLen int
+	IPv6Gateway         string
+	// the API reports the link-local address only for the primary network
+	LinkLocalIPv6Address   string
+	LinkLocalIPv6PrefixLen int
+}
+
+// byNetwork sorts addresses by network name, the primary network first
+type byNetwork struct {
+	addresses []NetworkAddress
+	primary   string
+}
+
+func (s byNetwork) Len() int {
+	return len(s.addresses)
+}
+func (s byNetwork) Swap(i, j int) {
+	s.addresses[i], s.addresses[j] = s.addresses[j], s.addresses[i]
+}
+func (s byNetwork) Less(i, j int) bool {
+	a, b := s.addresses[i].Network, s.addresses[j].Network
+	if a == s.primary || b == s.primary {
+		return a == s.primary && b != s.primary
+	}
+	return a < b
+}
+
+// primaryNetwork returns the network the container was created with
+func primaryNetwork(container *docker.Container) string {
+	if container.HostConfig == nil || container.HostConfig.NetworkMode == "" || container.HostConfig.NetworkMode == "default" {
+		return "bridge"
+	}
+	return container.HostConfig.NetworkMode
+}
+
+// GetAddresses returns the endpoints of a container on each network it is
+// attached to, the one it was created with first and then sorted by network
+// name; for daemons not reporting networks the legacy address is returned
+func GetAddresses(container *docker.Container) []NetworkAddress {
+	settings := container.NetworkSettings
+	if settings == nil {
+		return nil
+	}
+
+	var result []NetworkAddress
+	for name, network := range settings.Networks {
+		result = append(result, NetworkAddress{
+			Network:             name,
+			IPAddress:           network.IPAddress,
+			IPPrefixLen:         network.IPPrefixLen,
+			Gateway:             network.Gateway,
+			MacAddress:          network.MacAddress,
+			GlobalIPv6Address:   network.GlobalIPv6Address,
+			GlobalIPv6PrefixLen: network.GlobalIPv6PrefixLen,
+			IPv6Gateway:         network.IPv6Gateway,
+		})
+	}
+	sort.Sort(byNetwork{result, primaryNetwork(container)})
+
+	if len(result) == 0 && (settings.IPAddress != "" || settings.GlobalIPv6Address != "") {
+		result = append(result, NetworkAddress{
+			Network:             primaryNetwork(container),
+			IPAddress:           settings.IPAddress,
+			IPPrefixLen:         settings.IPPrefixLen,
+			Gateway:             settings.Gateway,
+			MacAddress:          settings.MacAddress,
+			GlobalIPv6Address:   settings.GlobalIPv6Address,
+			GlobalIPv6PrefixLen: settings.GlobalIPv6PrefixLen,
+			IPv6Gateway:         settings.IPv6Gateway,
+		})
+	}
+
+	if len(result) != 0 && result[0].Network == primaryNetwork(container) {
+		result[0].LinkLocalIPv6Address = settings.LinkLocalIPv6Address
+		result[0].LinkLocalIPv6PrefixLen = settings.LinkLocalIPv6PrefixLen
+	}
+
+	return result
+}
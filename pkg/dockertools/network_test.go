@@ -0,0 +1,108 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package dockertools
+
+import (
+	"github.com/gdm85/go-dockerclient"
+	"reflect"
+	"testing"
+)
+
+func TestGetAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		container docker.Container
+		want      []NetworkAddress
+	}{
+		{
+			name:      "no network settings",
+			container: docker.Container{},
+			want:      nil,
+		},
+		{
+			name:      "no address",
+			container: docker.Container{NetworkSettings: &docker.NetworkSettings{}},
+			want:      nil,
+		},
+		{
+			name: "legacy address only",
+			container: docker.Container{NetworkSettings: &docker.NetworkSettings{
+				IPAddress: "172.17.0.2", IPPrefixLen: 16, Gateway: "172.17.0.1",
+				LinkLocalIPv6Address: "fe80::42:acff:fe11:2", LinkLocalIPv6PrefixLen: 64,
+			}},
+			want: []NetworkAddress{
+				{Network: "bridge", IPAddress: "172.17.0.2", IPPrefixLen: 16, Gateway: "172.17.0.1", LinkLocalIPv6Address: "fe80::42:acff:fe11:2", LinkLocalIPv6PrefixLen: 64},
+			},
+		},
+		{
+			name: "empty legacy address",
+			container: docker.Container{
+				HostConfig: &docker.HostConfig{NetworkMode: "backend"},
+				NetworkSettings: &docker.NetworkSettings{Networks: map[string]docker.ContainerNetwork{
+					"backend": {IPAddress: "172.20.0.2", IPPrefixLen: 16, Gateway: "172.20.0.1"},
+				}},
+			},
+			want: []NetworkAddress{
+				{Network: "backend", IPAddress: "172.20.0.2", IPPrefixLen: 16, Gateway: "172.20.0.1"},
+			},
+		},
+		{
+			name: "several networks",
+			container: docker.Container{
+				HostConfig: &docker.HostConfig{NetworkMode: "frontend"},
+				NetworkSettings: &docker.NetworkSettings{
+					IPAddress: "172.17.0.3",
+					Networks: map[string]docker.ContainerNetwork{
+						"monitoring": {IPAddress: "172.22.0.3"},
+						"backend":    {IPAddress: "172.20.0.3", GlobalIPv6Address: "fd00:20::3", GlobalIPv6PrefixLen: 64},
+						"frontend":   {IPAddress: "172.21.0.3"},
+					},
+					LinkLocalIPv6Address: "fe80::42:acff:fe15:3",
+				},
+			},
+			want: []NetworkAddress{
+				{Network: "frontend", IPAddress: "172.21.0.3", LinkLocalIPv6Address: "fe80::42:acff:fe15:3"},
+				{Network: "backend", IPAddress: "172.20.0.3", GlobalIPv6Address: "fd00:20::3", GlobalIPv6PrefixLen: 64},
+				{Network: "monitoring", IPAddress: "172.22.0.3"},
+			},
+		},
+		{
+			name: "not on the primary network",
+			container: docker.Container{
+				HostConfig: &docker.HostConfig{NetworkMode: "default"},
+				NetworkSettings: &docker.NetworkSettings{
+					Networks: map[string]docker.ContainerNetwork{
+						"backend": {IPAddress: "172.20.0.4"},
+					},
+					LinkLocalIPv6Address: "fe80::42:acff:fe14:4",
+				},
+			},
+			want: []NetworkAddress{
+				{Network: "backend", IPAddress: "172.20.0.4"},
+			},
+		},
+	}
+	for _, test := range tests {
+		got := GetAddresses(&test.container)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdm85/goopt"
+	"net"
+	"os"
+	"sort"
+	"time"
+)
+
+var (
+	server = goopt.String([]string{"--server"}, "", "address of the DNS server to query")
+	useTCP = goopt.Flag([]string{"--tcp"}, []string{}, "query over TCP instead of UDP", "")
+)
+
+// describe reduces lookup errors to what is relevant to tests
+func describe(err error) string {
+	if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+		return "not found"
+	}
+	return "failed"
+}
+
+func main() {
+	goopt.Description = func() string {
+		return "Query A and AAAA records of names, or PTR records of addresses, from a single DNS server."
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "dns-query"
+	goopt.Parse(nil)
+
+	if *server == "" {
+		fmt.Fprintf(os.Stderr, "dns-query: --server is mandatory\n")
+		os.Exit(1)
+	}
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if *useTCP {
+				network = "tcp"
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, *server)
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, arg := range goopt.Args {
+		if net.ParseIP(arg) != nil {
+			names, err := resolver.LookupAddr(ctx, arg)
+			if err != nil {
+				fmt.Printf("%s PTR: %s\n", arg, describe(err))
+				continue
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("%s PTR %s\n", arg, name)
+			}
+			continue
+		}
+
+		for _, family := range []struct{ network, rtype string }{{"ip4", "A"}, {"ip6", "AAAA"}} {
+			ips, err := resolver.LookupIP(ctx, family.network, arg)
+			if err != nil {
+				fmt.Printf("%s %s: %s\n", arg, family.rtype, describe(err))
+				continue
+			}
+			addresses := make([]string, len(ips))
+			for i, ip := range ips {
+				addresses[i] = ip.String()
+			}
+			sort.Strings(addresses)
+			for _, address := range addresses {
+				fmt.Printf("%s %s %s\n", arg, family.rtype, address)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * docker-cli-tools v0.1.0
+ * Copyright (C) 2014 gdm85 - https://github.com/gdm85/docker-cli-tools/
+
+This program is free software; you can redistribute it and/or
+modify it under the terms of the GNU General Public License
+as published by the Free Software Foundation; either version 2
+of the License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program; if not, write to the Free Software
+Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+*/
+
+package main
+
+import (
+	"fmt"
+	"github.com/gdm85/docker-cli-tools/internal/fakeengine"
+	"github.com/gdm85/goopt"
+	"os"
+)
+
+var (
+	fixtures = goopt.String([]string{"--fixtures"}, "", "directory with fixture data")
+	socket   = goopt.String([]string{"--socket"}, "", "path of the unix socket to listen on")
+	actions  = goopt.String([]string{"--actions"}, "", "file to append requests changing containers to")
+)
+
+func main() {
+	goopt.Description = func() string {
+		return "Serve a fake Docker Engine API from fixture data."
+	}
+	goopt.Version = "0.1"
+	goopt.Summary = "fake-engine"
+	goopt.Parse(nil)
+
+	if *fixtures == "" || *socket == "" {
+		fmt.Fprintf(os.Stderr, "fake-engine: --fixtures and --socket are mandatory\n")
+		os.Exit(1)
+	}
+
+	server := fakeengine.New(*fixtures)
+	if *actions != "" {
+		f, err := os.OpenFile(*actions, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fake-engine: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		server.LogActions(f)
+	}
+	err := server.Listen("unix", *socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fake-engine: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = server.Serve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fake-engine: %s\n", err)
+		os.Exit(1)
+	}
+}
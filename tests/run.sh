@@ -0,0 +1,117 @@
+#!/bin/bash
+## run.sh
+##
+## golden-output tests: runs every tool against the fake Engine API (see
+## internal/fakeengine) and compares output with tests/golden/<case>.out
+##
+## usage: tests/run.sh [-u]
+##   -u  update golden files instead of comparing
+#
+
+TESTS="$(cd "$(dirname "$0")" && pwd)" || exit $?
+ROOT="$(dirname "$TESTS")"
+
+UPDATE=0
+if [[ "$1" == "-u" ]]; then
+	UPDATE=1
+fi
+
+TMPD="$(mktemp -d)" || exit $?
+function cleanup() {
+	for PID in $FAKE_PIDS; do
+		kill $PID 2>/dev/null
+		wait $PID 2>/dev/null
+	done
+	rm -rf "$TMPD"
+}
+trap cleanup EXIT
+
+## build all tools plus the fake engine
+mkdir "$TMPD/bin" || exit $?
+for T in docker-cli-tools docker-cpu-killers docker-dns docker-grep docker-hosts docker-images docker-ipv4 tests/dns-query tests/fake-engine; do
+	(cd "$ROOT/$T" && go build -o "$TMPD/bin/$(basename $T)") || exit $?
+done
+
+## symlinks to the multicall binary
+mkdir "$TMPD/links" && \
+"$TMPD/bin/docker-cli-tools" install-links "$TMPD/links" > /dev/null || exit $?
+
+## start_engine FIXTURES SOCKET
+## requests changing containers are recorded next to SOCKET, with extension .actions
+function start_engine() {
+	"$TMPD/bin/fake-engine" --fixtures "$1" --socket "$2" --actions "${2%.sock}.actions" &
+	FAKE_PIDS="$FAKE_PIDS $!"
+
+	## wait for the socket to show up
+	for I in $(seq 50); do
+		test -S "$2" && return 0
+		sleep 0.1
+	done
+	echo "run.sh: fake engine did not start" 1>&2
+	return 1
+}
+
+## the fake engine plus a second one, with tests/fixtures/staging, for the multi-host cases
+## and a third one, with tests/fixtures/problems, for the health and restart cases
+start_engine "$TESTS/fixtures" "$TMPD/docker.sock" && \
+start_engine "$TESTS/fixtures/staging" "$TMPD/staging.sock" && \
+start_engine "$TESTS/fixtures/problems" "$TMPD/problems.sock" || exit $?
+
+## isolate from the docker CLI configuration of the user
+unset DOCKER_CONTEXT DOCKER_TLS_VERIFY DOCKER_CERT_PATH
+export DOCKER_HOST="unix://$TMPD/docker.sock"
+export STAGING_HOST="unix://$TMPD/staging.sock"
+export PROBLEMS_HOST="unix://$TMPD/problems.sock"
+export ACTIONS="$TMPD/docker.actions"
+export STAGING_ACTIONS="$TMPD/staging.actions"
+export DOCKER_CONFIG="$TMPD/config"
+export FIXTURES="$TESTS/fixtures"
+export LINKS="$TMPD/links"
+
+## docker CLI context named 'fake' pointing to the fake engine, also set as current context
+CONTEXT_ID="$(echo -n fake | sha256sum | awk '{ print $1 }')" && \
+mkdir -p "$DOCKER_CONFIG/contexts/meta/$CONTEXT_ID" && \
+echo "{\"Name\":\"fake\",\"Metadata\":{},\"Endpoints\":{\"docker\":{\"Host\":\"$DOCKER_HOST\",\"SkipTLSVerify\":false}}}" > "$DOCKER_CONFIG/contexts/meta/$CONTEXT_ID/meta.json" && \
+echo '{"currentContext":"fake"}' > "$DOCKER_CONFIG/config.json" || exit $?
+export PATH="$TMPD/bin:$FIXTURES/bin:$PATH"
+
+mkdir -p "$TESTS/golden" || exit $?
+FAILED=0
+TOTAL=0
+while IFS='|' read -r NAME CMD; do
+	if [[ -z "$NAME" || "$NAME" == \#* ]]; then
+		continue
+	fi
+	let TOTAL=TOTAL+1
+
+	OUT="$TMPD/$NAME.out"
+	bash -c "$CMD" > "$OUT" 2>&1
+	RV=$?
+	if [ ! $RV -eq 0 ]; then
+		echo "exit status $RV" >> "$OUT"
+	fi
+	## socket paths are under the temporary directory
+	sed -i "s|$TMPD|\$TMPD|g" "$OUT" || exit $?
+
+	GOLDEN="$TESTS/golden/$NAME.out"
+	if [ $UPDATE -eq 1 ]; then
+		cp "$OUT" "$GOLDEN" || exit $?
+		continue
+	fi
+
+	if ! diff -u "$GOLDEN" "$OUT"; then
+		echo "FAIL: $NAME: $CMD" 1>&2
+		let FAILED=FAILED+1
+	fi
+done < "$TESTS/cases"
+
+if [ $UPDATE -eq 1 ]; then
+	echo "updated $TOTAL golden files"
+	exit 0
+fi
+
+if [ ! $FAILED -eq 0 ]; then
+	echo "$FAILED of $TOTAL cases failed" 1>&2
+	exit 1
+fi
+echo "all $TOTAL cases passed"